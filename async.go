@@ -0,0 +1,93 @@
+package saver
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned when an async saver can not queue more requests.
+var ErrQueueFull error = errors.New("queue full")
+
+// ErrQueueClosed is returned when an async saver is already closed.
+var ErrQueueClosed error = errors.New("queue closed")
+
+// RequestSaverAsync saves requests on background goroutines.
+//
+// A queued request must not refer to request scoped data(e.g, an unread *http.Request body).
+type RequestSaverAsync[Q, R any] struct {
+	lock    sync.RWMutex
+	closed  bool
+	queue   chan Q
+	workers sync.WaitGroup
+}
+
+// RequestSaverAsyncNew creates an async saver and starts its workers.
+//
+// # Arguments
+//   - original: Saves a request.
+//   - workers: Number of goroutines(at least 1).
+//   - queueSize: Number of requests which can wait.
+//   - onResult: Gets a save result.
+func RequestSaverAsyncNew[Q, R any](
+	original RequestSaver[Q, R],
+	workers, queueSize int,
+	onResult func(request Q, result R, e error),
+) *RequestSaverAsync[Q, R] {
+	if workers < 1 {
+		workers = 1
+	}
+	var a *RequestSaverAsync[Q, R] = &RequestSaverAsync[Q, R]{
+		queue: make(chan Q, queueSize),
+	}
+	a.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer a.workers.Done()
+			for request := range a.queue {
+				result, e := original(request)
+				onResult(request, result, e)
+			}
+		}()
+	}
+	return a
+}
+
+// Enqueue queues a request without blocking.
+//
+// # Returns
+//   - ErrQueueFull if the queue is full.
+//   - ErrQueueClosed if the saver is closed.
+func (a *RequestSaverAsync[Q, R]) Enqueue(request Q) error {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case a.queue <- request:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// AsRequestSaver creates a request saver which only queues requests.
+//
+// The result(true) means that the request was queued; ResultWriterAcceptedNew can write it.
+func (a *RequestSaverAsync[Q, R]) AsRequestSaver() RequestSaver[Q, bool] {
+	return func(request Q) (queued bool, e error) {
+		e = a.Enqueue(request)
+		return nil == e, e
+	}
+}
+
+// Close stops accepting requests and waits until queued requests are saved.
+func (a *RequestSaverAsync[Q, R]) Close() {
+	a.lock.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.lock.Unlock()
+	a.workers.Wait()
+}
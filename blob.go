@@ -0,0 +1,212 @@
+package saver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrBlobMismatch is returned when a blob does not match its reference.
+var ErrBlobMismatch error = errors.New("blob does not match the reference")
+
+// ErrBodyUnresolved is returned when a body reference is used without a blob store.
+var ErrBodyUnresolved error = errors.New("request body is kept in a blob store")
+
+// BlobRef refers to a body kept in a BlobStore.
+type BlobRef struct {
+	Digest string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// BlobRefNew creates a reference of a blob.
+func BlobRefNew(blob []byte) BlobRef {
+	var sum [sha256.Size]byte = sha256.Sum256(blob)
+	return BlobRef{Digest: hex.EncodeToString(sum[:]), Size: int64(len(blob))}
+}
+
+// Check checks the size and the digest of a blob.
+func (r BlobRef) Check(blob []byte) error {
+	if BlobRefNew(blob) != r {
+		return fmt.Errorf("%w: %s(%d bytes)", ErrBlobMismatch, r.Digest, r.Size)
+	}
+	return nil
+}
+
+// BlobStore keeps blobs addressed by the hex encoded SHA-256 of the content.
+type BlobStore interface {
+	// PutBlob stores a blob; an existing blob of the same digest may be kept.
+	PutBlob(digest string, blob []byte) error
+
+	// GetBlob gets a blob.
+	GetBlob(digest string) ([]byte, error)
+}
+
+// BlobDir is a BlobStore which keeps a blob as a file: <dir>/<2 digits>/<sha256>.
+type BlobDir struct {
+	dir   string
+	idGen RecordIDGen
+}
+
+// BlobDirNew creates a blob store(the directory is created if missing).
+func BlobDirNew(dir string) (*BlobDir, error) {
+	var e error = os.MkdirAll(dir, 0755)
+	if nil != e {
+		return nil, e
+	}
+	return &BlobDir{dir: dir, idGen: RecordIDGenRandom}, nil
+}
+
+func (d *BlobDir) path(digest string) (string, error) {
+	if !casDigestPattern.MatchString(digest) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDigest, digest)
+	}
+	return filepath.Join(d.dir, digest[:2], digest), nil
+}
+
+// put writes a blob unless a blob of the digest exists.
+func (d *BlobDir) put(digest string, blob []byte) (stored bool, e error) {
+	path, e := d.path(digest)
+	if nil != e {
+		return false, e
+	}
+	_, e = os.Stat(path)
+	if nil == e {
+		return false, nil
+	}
+	if !errors.Is(e, fs.ErrNotExist) {
+		return false, e
+	}
+	e = os.MkdirAll(filepath.Dir(path), 0755)
+	if nil != e {
+		return false, e
+	}
+	// a concurrent writer of the same blob writes the same content
+	var tmp string = path + "." + d.idGen() + ".tmp"
+	f, e := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if nil != e {
+		return false, e
+	}
+	_, e = f.Write(blob)
+	if nil == e {
+		e = f.Sync()
+	}
+	e = errors.Join(e, f.Close())
+	if nil == e {
+		e = os.Rename(tmp, path)
+	}
+	if nil != e {
+		return false, errors.Join(e, os.Remove(tmp))
+	}
+	return true, nil
+}
+
+// PutBlob stores a blob unless a blob of the digest exists.
+func (d *BlobDir) PutBlob(digest string, blob []byte) error {
+	_, e := d.put(digest, blob)
+	return e
+}
+
+// GetBlob gets a blob.
+func (d *BlobDir) GetBlob(digest string) ([]byte, error) {
+	path, e := d.path(digest)
+	if nil != e {
+		return nil, e
+	}
+	return os.ReadFile(path)
+}
+
+// ExchangeSpillNew creates a func which moves a large request body to a blob store.
+//
+// The body of a spilled exchange is empty and its BodyRef refers to the blob.
+//
+// # Arguments
+//   - store: Keeps large bodies.
+//   - threshold: Bodies larger than this(in bytes) are spilled.
+func ExchangeSpillNew(store BlobStore, threshold int) func(x ExchangeStd) (ExchangeStd, error) {
+	return func(x ExchangeStd) (ExchangeStd, error) {
+		var q Request[http.Header, []byte] = Request[http.Header, []byte](x.Request)
+		var body []byte = q.Body()
+		if nil != x.BodyRef || len(body) <= threshold {
+			return x, nil
+		}
+		var ref BlobRef = BlobRefNew(body)
+		var e error = store.PutBlob(ref.Digest, body)
+		if nil != e {
+			return x, e
+		}
+		x.Request = RequestStd(RequestNew(q.Header(), []byte{}))
+		x.BodyRef = &ref
+		return x, nil
+	}
+}
+
+// ExchangeStd2bytesSpillNew creates a serializer which keeps large request bodies in a blob store.
+//
+// Serialized exchanges keep the size and the digest of a spilled body only(sample: a small redis list).
+//
+// # Arguments
+//   - store: Keeps large bodies.
+//   - threshold: Bodies larger than this(in bytes) are spilled.
+//   - serializer: Serializes an exchange(sample: ExchangeStd2bytesTar).
+func ExchangeStd2bytesSpillNew(store BlobStore, threshold int, serializer ExchangeStd2bytes) ExchangeStd2bytes {
+	return Compose(ExchangeSpillNew(store, threshold), serializer)
+}
+
+// RequestStd2bytesSpillNew creates a request serializer which keeps large request bodies in a blob store.
+//
+// Requests are serialized by ExchangeStd2bytesTar(headers and a body as RequestSerializerNewGenericTar and a request line).
+//
+// # Arguments
+//   - store: Keeps large bodies.
+//   - threshold: Bodies larger than this(in bytes) are spilled.
+//   - limit: Number of body bytes to read(resource limit).
+func RequestStd2bytesSpillNew(store BlobStore, threshold int, limit int64) RequestStd2bytes {
+	var serializer ExchangeStd2bytes = ExchangeStd2bytesSpillNew(store, threshold, ExchangeStd2bytesTar)
+	return func(q *http.Request) (serialized []byte, e error) {
+		body, e := io.ReadAll(io.LimitReader(q.Body, limit))
+		if nil != e {
+			return nil, e
+		}
+		return serializer(ExchangeStdNew(q, body, "", time.Time{}))
+	}
+}
+
+// ExchangeResolveNew creates a func which restores a request body kept in a blob store.
+//
+// A blob is checked against its size and digest; an exchange without a reference is unchanged.
+//
+// # Arguments
+//   - store: Keeps spilled bodies(nil: ErrBodyUnresolved for a spilled body).
+func ExchangeResolveNew(store BlobStore) func(x ExchangeStd) (ExchangeStd, error) {
+	return func(x ExchangeStd) (ExchangeStd, error) {
+		if nil == x.BodyRef {
+			return x, nil
+		}
+		if nil == store {
+			return x, fmt.Errorf("%w: %s", ErrBodyUnresolved, x.BodyRef.Digest)
+		}
+		body, e := store.GetBlob(x.BodyRef.Digest)
+		if nil == e {
+			e = x.BodyRef.Check(body)
+		}
+		if nil != e {
+			return x, e
+		}
+		var q Request[http.Header, []byte] = Request[http.Header, []byte](x.Request)
+		x.Request = RequestStd(RequestNew(q.Header(), body))
+		x.BodyRef = nil
+		return x, nil
+	}
+}
+
+// ResolveBodies creates a source which restores request bodies kept in a blob store.
+func (s ExchangeSource) ResolveBodies(store BlobStore) ExchangeSource {
+	return s.Map(ExchangeResolveNew(store))
+}
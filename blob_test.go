@@ -0,0 +1,158 @@
+package saver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestBlob(t *testing.T) {
+	t.Parallel()
+
+	var exchangeNew func(id, body string) saver.ExchangeStd = func(id, body string) saver.ExchangeStd {
+		var q = httptest.NewRequest("PUT", "/uploads/"+id, strings.NewReader(body))
+		q.Header.Set("Content-Type", "application/octet-stream")
+		return saver.ExchangeStdNew(q, []byte(body), id, time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC))
+	}
+	var bodyOf func(x saver.ExchangeStd) string = func(x saver.ExchangeStd) string {
+		return string(saver.Request[http.Header, []byte](x.Request).Body())
+	}
+	var large string = strings.Repeat("0123456789", 100)
+
+	t.Run("spill and resolve", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string = t.TempDir()
+		blobs, e := saver.BlobDirNew(dir)
+		t.Run("no open error", assertNil(e))
+
+		var serializer saver.ExchangeStd2bytes = saver.ExchangeStd2bytesSpillNew(blobs, 64, saver.ExchangeStd2bytesTar)
+		small, e := serializer(exchangeNew("small", "hw"))
+		t.Run("no small error", assertNil(e))
+		spilled, e := serializer(exchangeNew("large", large))
+		t.Run("no large error", assertNil(e))
+		t.Run("body not serialized", assertTrue(!bytes.Contains(spilled, []byte(large))))
+
+		var ref saver.BlobRef = saver.BlobRefNew([]byte(large))
+		stored, _ := os.ReadFile(filepath.Join(dir, ref.Digest[:2], ref.Digest))
+		t.Run("blob", assertEq(string(stored), large))
+
+		parsed, e := saver.ExchangeStdFromTar(spilled)
+		t.Run("no parse error", assertNil(e))
+		t.Run("parsed reference", assertEq(*parsed.BodyRef, ref))
+		t.Run("empty body", assertEq(bodyOf(parsed), ""))
+		var header http.Header = saver.Request[http.Header, []byte](parsed.Request).Header()
+		t.Run("header kept", assertEq(header.Get("Content-Type"), "application/octet-stream"))
+
+		var src saver.ExchangeSource = saver.ExchangeSourceNewBlobs([][]byte{small, spilled}).ResolveBodies(blobs)
+		var bodies []string
+		for {
+			x, e := src()
+			if nil != e {
+				t.Run("eof", assertTrue(errors.Is(e, io.EOF)))
+				break
+			}
+			t.Run("resolved "+x.ID, assertTrue(nil == x.BodyRef))
+			bodies = append(bodies, bodyOf(x))
+		}
+		t.Run("bodies", assertEq(strings.Join(bodies, ","), "hw,"+large))
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		t.Parallel()
+
+		blobs, _ := saver.BlobDirNew(t.TempDir())
+		x, _ := saver.ExchangeSpillNew(blobs, 0)(exchangeNew("a", "abc"))
+
+		_, e := saver.ExchangeResolveNew(nil)(x)
+		t.Run("no store", assertTrue(errors.Is(e, saver.ErrBodyUnresolved)))
+
+		_, e = saver.ExchangeResolveNew(blobs)(exchangeNew("b", "inline"))
+		t.Run("inline body", assertNil(e))
+
+		_ = blobs.PutBlob(saver.BlobRefNew([]byte("abc")).Digest, []byte("abc"))
+		var tampered saver.ExchangeStd = x
+		tampered.BodyRef = &saver.BlobRef{Digest: x.BodyRef.Digest, Size: 4}
+		_, e = saver.ExchangeResolveNew(blobs)(tampered)
+		t.Run("size mismatch", assertTrue(errors.Is(e, saver.ErrBlobMismatch)))
+
+		_, e = blobs.GetBlob("../x")
+		t.Run("invalid digest", assertTrue(errors.Is(e, saver.ErrInvalidDigest)))
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		t.Parallel()
+
+		var received chan string = make(chan string, 1)
+		var svr *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+			body, _ := io.ReadAll(q.Body)
+			received <- string(body)
+		}))
+		t.Cleanup(svr.Close)
+		target, _ := url.Parse(svr.URL)
+
+		blobs, _ := saver.BlobDirNew(t.TempDir())
+		spilled, _ := saver.ExchangeStd2bytesSpillNew(blobs, 64, saver.ExchangeStd2bytesTar)(exchangeNew("large", large))
+
+		var p *saver.Replayer = saver.ReplayerNew(target, 0, 1)
+		p.Blobs = blobs
+		var results []saver.ReplayResult
+		e := p.Replay(context.Background(), saver.ExchangeSourceNewBlobs([][]byte{spilled}), func(r saver.ReplayResult) {
+			results = append(results, r)
+		})
+		t.Run("no replay error", assertNil(e))
+		t.Run("no result error", assertNil(results[0].Err))
+		t.Run("original body", assertEq(<-received, large))
+	})
+
+	t.Run("RequestStd2bytesSpillNew", func(t *testing.T) {
+		t.Parallel()
+
+		blobs, _ := saver.BlobDirNew(t.TempDir())
+		var saved [][]byte
+		var list saver.BytesSaver = func(serialized []byte) (int64, error) {
+			saved = append(saved, bytes.Clone(serialized))
+			return int64(len(serialized)), nil
+		}
+		var sav saver.RequestSaverStd[int64] = list.NewRequestSaverStd(saver.RequestStd2bytesSpillNew(blobs, 64, 1<<20))
+		_, e := sav(httptest.NewRequest("POST", "/api/v1/write", strings.NewReader(large)))
+		t.Run("no save error", assertNil(e))
+		t.Run("body not serialized", assertTrue(!bytes.Contains(saved[0], []byte(large))))
+
+		x, e := saver.ExchangeSourceNewBlobs(saved).ResolveBodies(blobs)()
+		t.Run("no resolve error", assertNil(e))
+		t.Run("request line", assertEq(x.Method+" "+x.URI, "POST /api/v1/write"))
+		t.Run("body", assertEq(bodyOf(x), large))
+	})
+
+	t.Run("VCR", func(t *testing.T) {
+		t.Parallel()
+
+		blobs, _ := saver.BlobDirNew(t.TempDir())
+		var x saver.ExchangeStd = exchangeNew("large", large)
+		x.Response = &saver.ResponseStd{Status: 201, Body: []byte("uploaded")}
+		spilled, _ := saver.ExchangeStd2bytesSpillNew(blobs, 64, saver.ExchangeStd2bytesTar)(x)
+
+		var v *saver.VCR = saver.VCRNew(saver.VCRKeyDefault, 1<<20)
+		e := v.Load(saver.ExchangeSourceNewBlobs([][]byte{spilled}))
+		t.Run("unresolved", assertTrue(errors.Is(e, saver.ErrBodyUnresolved)))
+
+		v.Blobs = blobs
+		e = v.Load(saver.ExchangeSourceNewBlobs([][]byte{spilled}))
+		t.Run("no load error", assertNil(e))
+		var w *httptest.ResponseRecorder = httptest.NewRecorder()
+		v.ServeHTTP(w, httptest.NewRequest("PUT", "/uploads/large", strings.NewReader(large)))
+		t.Run("matched by the body", assertEq(w.Code, 201))
+	})
+}
@@ -0,0 +1,190 @@
+package saver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrInvalidDigest is returned when a digest is not a hex encoded SHA-256.
+var ErrInvalidDigest error = errors.New("invalid digest")
+
+var casDigestPattern *regexp.Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// CASRef is an entry of the reference log: a saved exchange and its blob.
+type CASRef struct {
+	Time    time.Time     `json:"time"`
+	ID      string        `json:"id,omitempty"`
+	Remote  string        `json:"remote,omitempty"`
+	Latency time.Duration `json:"latency,omitempty"`
+	Digest  string        `json:"sha256"`
+	Size    int64         `json:"size"`
+	Body    string        `json:"body,omitempty"` // the digest of the request body blob
+}
+
+// ExchangeStdCanonical removes values which differ for each delivery of the same request.
+//
+// The record id, the time, the remote address and the latency are kept in a CASRef instead.
+func ExchangeStdCanonical(x ExchangeStd) (canonical ExchangeStd, ref CASRef) {
+	ref = CASRef{Time: x.Time, ID: x.ID, Remote: x.RemoteAddr}
+	canonical = x
+	canonical.ID = ""
+	canonical.Time = time.Time{}
+	canonical.RemoteAddr = ""
+	if nil != x.Response {
+		var res ResponseStd = *x.Response
+		ref.Latency = res.Latency
+		res.Latency = 0
+		canonical.Response = &res
+	}
+	return canonical, ref
+}
+
+// CASStore is a content-addressed store which keeps identical exchanges once.
+//
+//   - blobs/<2 digits>/<sha256>: Request bodies and canonical serialized exchanges(ExchangeStd2bytesTar).
+//   - refs.jsonl: The reference log(a JSON line per saved exchange in the saved order).
+//
+// A request body is kept as a blob of its own; deliveries which differ in headers only share the body.
+type CASStore struct {
+	lock  sync.Mutex
+	dir   string
+	blobs *BlobDir
+	refs  *os.File
+}
+
+// CASStoreNew opens a store(created if missing).
+//
+// # Arguments
+//   - dir: The store directory.
+func CASStoreNew(dir string) (*CASStore, error) {
+	blobs, e := BlobDirNew(filepath.Join(dir, "blobs"))
+	if nil != e {
+		return nil, e
+	}
+	refs, e := os.OpenFile(filepath.Join(dir, "refs.jsonl"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if nil != e {
+		return nil, e
+	}
+	return &CASStore{dir: dir, blobs: blobs, refs: refs}, nil
+}
+
+// Put stores a blob unless a blob of the same digest exists.
+func (s *CASStore) Put(blob []byte) (digest string, stored bool, e error) {
+	var ref BlobRef = BlobRefNew(blob)
+	stored, e = s.blobs.put(ref.Digest, blob)
+	return ref.Digest, stored, e
+}
+
+// Get gets a blob.
+func (s *CASStore) Get(digest string) ([]byte, error) { return s.blobs.GetBlob(digest) }
+
+// Append writes a reference to the log.
+func (s *CASStore) Append(ref CASRef) error {
+	line, e := json.Marshal(ref)
+	if nil != e {
+		return e
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, e = s.refs.Write(append(line, '\n'))
+	return e
+}
+
+// Close closes the reference log.
+func (s *CASStore) Close() error { return s.refs.Close() }
+
+// NewExchangeSaver creates a saver which stores the canonical exchange and appends a reference.
+//
+// The result is the number of newly stored bytes(0 for a duplicate).
+// An exchange spilled by ExchangeStd2bytesSpillNew keeps its body reference.
+func (s *CASStore) NewExchangeSaver() ExchangeSaver[int64] {
+	return func(x ExchangeStd) (stored int64, e error) {
+		canonical, ref := ExchangeStdCanonical(x)
+		if nil == canonical.BodyRef {
+			var q Request[http.Header, []byte] = Request[http.Header, []byte](canonical.Request)
+			var body BlobRef = BlobRefNew(q.Body())
+			isNew, e := s.blobs.put(body.Digest, q.Body())
+			if nil != e {
+				return 0, e
+			}
+			if isNew {
+				stored += body.Size
+			}
+			canonical.Request = RequestStd(RequestNew(q.Header(), []byte{}))
+			canonical.BodyRef = &body
+			ref.Body = body.Digest
+		}
+		blob, e := ExchangeStd2bytesTar(canonical)
+		if nil != e {
+			return 0, e
+		}
+		digest, isNew, e := s.Put(blob)
+		if nil != e {
+			return 0, e
+		}
+		ref.Digest = digest
+		ref.Size = int64(len(blob))
+		if isNew {
+			stored += ref.Size
+		}
+		return stored, s.Append(ref)
+	}
+}
+
+// NewBytesSaver creates a saver of serialized exchanges(tar archives created by ExchangeStd2bytesTar).
+func (s *CASStore) NewBytesSaver() BytesSaver {
+	return BytesSaver(RequestSaverNew(ExchangeStdFromTar, s.NewExchangeSaver()))
+}
+
+// Exchange gets a saved exchange of a reference.
+func (s *CASStore) Exchange(ref CASRef) (x ExchangeStd, e error) {
+	blob, e := s.Get(ref.Digest)
+	if nil != e {
+		return x, e
+	}
+	x, e = ExchangeStdFromTar(blob)
+	if nil == e && "" != ref.Body {
+		x, e = ExchangeResolveNew(s.blobs)(x)
+	}
+	x.ID, x.Time, x.RemoteAddr = ref.ID, ref.Time, ref.Remote
+	if nil != x.Response {
+		x.Response.Latency = ref.Latency
+	}
+	return x, e
+}
+
+// Refs creates a reader of the reference log; io.EOF means no more references.
+func (s *CASStore) Refs() (next func() (CASRef, error), closer io.Closer, e error) {
+	f, e := os.Open(filepath.Join(s.dir, "refs.jsonl"))
+	if nil != e {
+		return nil, nil, e
+	}
+	var dec *json.Decoder = json.NewDecoder(bufio.NewReader(f))
+	return func() (ref CASRef, e error) {
+		e = dec.Decode(&ref)
+		return ref, e
+	}, f, nil
+}
+
+// Source creates a source of saved exchanges in the saved order(sample: Replayer).
+func (s *CASStore) Source() (ExchangeSource, io.Closer, error) {
+	next, closer, e := s.Refs()
+	if nil != e {
+		return nil, nil, e
+	}
+	return func() (x ExchangeStd, e error) {
+		ref, e := next()
+		if nil != e {
+			return x, e
+		}
+		return s.Exchange(ref)
+	}, closer, nil
+}
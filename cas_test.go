@@ -0,0 +1,149 @@
+package saver_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestCAS(t *testing.T) {
+	t.Parallel()
+
+	var exchangeNew func(id, body string, received time.Time) saver.ExchangeStd = func(
+		id, body string,
+		received time.Time,
+	) saver.ExchangeStd {
+		var q = httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+		q.Header.Set("Content-Type", "application/json")
+		q.RemoteAddr = "192.0.2.1:" + id
+		var x saver.ExchangeStd = saver.ExchangeStdNew(q, []byte(body), id, received)
+		x.Response = &saver.ResponseStd{Status: 200, Latency: time.Duration(len(id)) * time.Millisecond}
+		return x
+	}
+
+	t.Run("dedup", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string = t.TempDir()
+		s, e := saver.CASStoreNew(dir)
+		t.Run("no open error", assertNil(e))
+		defer s.Close()
+
+		var t0 time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		var sav saver.ExchangeSaver[int64] = s.NewExchangeSaver()
+		first, e := sav(exchangeNew("1", `{"event":"paid"}`, t0))
+		t.Run("no save error", assertNil(e))
+		t.Run("stored", assertTrue(0 < first))
+
+		// a retried webhook: same payload, another id, time and client port
+		retried, e := sav(exchangeNew("22", `{"event":"paid"}`, t0.Add(time.Second)))
+		t.Run("no retry error", assertNil(e))
+		t.Run("not stored again", assertEq(retried, 0))
+
+		serialized, _ := saver.ExchangeStd2bytesTar(exchangeNew("3", `{"event":"refunded"}`, t0.Add(2*time.Second)))
+		other, _ := s.NewBytesSaver()(serialized)
+		t.Run("other payload stored", assertTrue(0 < other))
+
+		blobs, _ := filepath.Glob(filepath.Join(dir, "blobs", "*", "*"))
+		t.Run("bodies and exchanges", assertEq(len(blobs), 4))
+
+		next, closer, e := s.Refs()
+		t.Run("no refs error", assertNil(e))
+		defer closer.Close()
+		var refs []saver.CASRef
+		for {
+			ref, e := next()
+			if nil != e {
+				t.Run("eof", assertTrue(errors.Is(e, io.EOF)))
+				break
+			}
+			refs = append(refs, ref)
+		}
+		t.Run("refs", assertEq(len(refs), 3))
+		t.Run("same digest", assertEq(refs[0].Digest, refs[1].Digest))
+		t.Run("time ordered", assertTrue(refs[0].Time.Before(refs[1].Time)))
+		t.Run("ref id", assertEq(refs[1].ID, "22"))
+
+		restored, e := s.Exchange(refs[1])
+		t.Run("no exchange error", assertNil(e))
+		t.Run("id", assertEq(restored.ID, "22"))
+		t.Run("remote", assertEq(restored.RemoteAddr, "192.0.2.1:22"))
+		t.Run("time", assertTrue(restored.Time.Equal(t0.Add(time.Second))))
+		t.Run("latency", assertEq(restored.Response.Latency, 2*time.Millisecond))
+		t.Run("body", assertEq(string(saver.Request[http.Header, []byte](restored.Request).Body()), `{"event":"paid"}`))
+	})
+
+	t.Run("different headers", func(t *testing.T) {
+		t.Parallel()
+
+		s, _ := saver.CASStoreNew(t.TempDir())
+		defer s.Close()
+		var body string = strings.Repeat(`{"event":"paid"}`, 1024)
+		var t0 time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		var first saver.ExchangeStd = exchangeNew("1", body, t0)
+		var retried saver.ExchangeStd = exchangeNew("2", body, t0.Add(time.Minute))
+		saver.Request[http.Header, []byte](retried.Request).Header().Set("X-Delivery-Attempt", "2")
+
+		var sav saver.ExchangeSaver[int64] = s.NewExchangeSaver()
+		stored, _ := sav(first)
+		t.Run("body stored", assertTrue(int64(len(body)) < stored))
+		stored, e := sav(retried)
+		t.Run("no retry error", assertNil(e))
+		t.Run("body not stored again", assertTrue(0 < stored && stored < int64(len(body))))
+
+		next, closer, _ := s.Refs()
+		defer closer.Close()
+		a, _ := next()
+		b, _ := next()
+		t.Run("different exchanges", assertTrue(a.Digest != b.Digest))
+		t.Run("same body", assertEq(a.Body, b.Body))
+
+		restored, e := s.Exchange(b)
+		t.Run("no exchange error", assertNil(e))
+		var q saver.Request[http.Header, []byte] = saver.Request[http.Header, []byte](restored.Request)
+		t.Run("body", assertEq(string(q.Body()), body))
+		t.Run("header", assertEq(q.Header().Get("X-Delivery-Attempt"), "2"))
+		t.Run("resolved", assertTrue(nil == restored.BodyRef))
+	})
+
+	t.Run("source", func(t *testing.T) {
+		t.Parallel()
+
+		s, _ := saver.CASStoreNew(t.TempDir())
+		defer s.Close()
+		for _, id := range []string{"a", "b"} {
+			_, _ = s.NewExchangeSaver()(exchangeNew(id, "same", time.Now()))
+		}
+		src, closer, e := s.Source()
+		t.Run("no source error", assertNil(e))
+		defer closer.Close()
+		var ids []string
+		for {
+			x, e := src()
+			if nil != e {
+				break
+			}
+			ids = append(ids, x.ID)
+		}
+		t.Run("every reference", assertEq(strings.Join(ids, ","), "a,b"))
+	})
+
+	t.Run("invalid digest", func(t *testing.T) {
+		t.Parallel()
+
+		s, _ := saver.CASStoreNew(t.TempDir())
+		defer s.Close()
+		_, e := s.Get("../../etc/passwd")
+		t.Run("rejected", assertTrue(errors.Is(e, saver.ErrInvalidDigest)))
+		_, e = s.Get(strings.Repeat("0", 64))
+		t.Run("missing", assertTrue(errors.Is(e, os.ErrNotExist)))
+	})
+}
@@ -0,0 +1,262 @@
+package saver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidGroup is returned when a consumer group name can not be used as a directory name.
+var ErrInvalidGroup error = errors.New("invalid consumer group")
+
+var consumerGroupPattern *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// Delivery is a record delivered to a consumer.
+type Delivery struct {
+	// Name is the name of the record.
+	Name string
+
+	// Data is the serialized request.
+	Data []byte
+
+	// Attempts is the number of deliveries including this one.
+	Attempts int
+
+	// Deadline is the end of the visibility timeout; the record is delivered again after it unless acked.
+	Deadline time.Time
+
+	token string
+}
+
+// Consumer pulls saved records of a consumer group.
+//
+// Every group gets every record; a record is delivered at least once per group.
+// Implemented by ConsumerDir(saved files) and SpoolConsumer(a Spool).
+type Consumer interface {
+	// Pull gets up to n records and hides them from other consumers of the group until the visibility timeout.
+	Pull(n int, visibility time.Duration) ([]Delivery, error)
+
+	// Ack marks a record as processed by the group.
+	Ack(d Delivery) error
+
+	// Nack makes a record visible again immediately.
+	Nack(d Delivery) error
+}
+
+// ConsumerDir consumes records saved as files in a directory(sample: RequestSaverNewFsSelfChecked).
+//
+// State files of a group are kept in a separate directory:
+//   - <state>/<group>/lease/<name>: attempts, deadline and a lease token.
+//   - <state>/<group>/ack/<name>: An empty marker of a processed record.
+//
+// Leases are created with a hard link and taken over with a rename; several processes can share a group.
+type ConsumerDir struct {
+	records string
+	leases  string
+	acks    string
+	idGen   RecordIDGen
+	now     func() time.Time
+}
+
+// ConsumerDirNew creates a consumer and state directories of the group.
+//
+// # Arguments
+//   - records: A directory of saved records(names starting with "." are ignored).
+//   - state: A directory of consumer groups(must not be the records directory).
+//   - group: The consumer group name.
+//   - now: Gets the current time(sample: time.Now); used for lease deadlines.
+func ConsumerDirNew(records, state, group string, now func() time.Time) (*ConsumerDir, error) {
+	if !consumerGroupPattern.MatchString(group) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidGroup, group)
+	}
+	var c *ConsumerDir = &ConsumerDir{
+		records: records,
+		leases:  filepath.Join(state, group, "lease"),
+		acks:    filepath.Join(state, group, "ack"),
+		idGen:   RecordIDGenRandom,
+		now:     now,
+	}
+	for _, dir := range []string{c.leases, c.acks} {
+		var e error = os.MkdirAll(dir, 0755)
+		if nil != e {
+			return nil, e
+		}
+	}
+	return c, nil
+}
+
+// consumerLease is the content of a lease file.
+type consumerLease struct {
+	attempts int
+	deadline int64 // unix nanoseconds
+	token    string
+}
+
+func (l consumerLease) bytes() []byte {
+	return []byte(fmt.Sprintf("%d %d %s\n", l.attempts, l.deadline, l.token))
+}
+
+func consumerLeaseRead(path string) (l consumerLease, e error) {
+	content, e := os.ReadFile(path)
+	if nil != e {
+		return l, e
+	}
+	_, e = fmt.Sscan(string(content), &l.attempts, &l.deadline, &l.token)
+	return l, e
+}
+
+// consumerLeaseCreate writes a lease file if it does not exist.
+//
+// The content is written to a temporary file which is linked to the lease; a lease is never read partially.
+func consumerLeaseCreate(path string, l consumerLease) (created bool, e error) {
+	var tmp string = filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"."+l.token)
+	e = os.WriteFile(tmp, l.bytes(), 0644)
+	if nil != e {
+		return false, e
+	}
+	e = os.Link(tmp, path)
+	if errors.Is(e, fs.ErrExist) {
+		return false, os.Remove(tmp)
+	}
+	return nil == e, errors.Join(e, os.Remove(tmp))
+}
+
+// lease tries to take a lease of a record.
+func (c *ConsumerDir) lease(name string, visibility time.Duration) (l consumerLease, leased bool, e error) {
+	var path string = filepath.Join(c.leases, name)
+	var now time.Time = c.now()
+	l = consumerLease{deadline: now.Add(visibility).UnixNano(), token: c.idGen()}
+
+	current, e := consumerLeaseRead(path)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+	case nil != e:
+		return l, false, e
+	case now.UnixNano() < current.deadline:
+		return l, false, nil // leased by another consumer
+	default:
+		// take over an expired lease: only one consumer can rename it
+		var expired string = filepath.Join(c.leases, "."+name+"."+l.token)
+		e = os.Rename(path, expired)
+		if errors.Is(e, fs.ErrNotExist) {
+			return l, false, nil
+		}
+		if nil != e {
+			return l, false, e
+		}
+		current, e = consumerLeaseRead(expired) // an unreadable lease is expired
+		if nil == e && now.UnixNano() < current.deadline {
+			// renewed by another consumer after the first read
+			_ = os.Link(expired, path) // fails if leased again
+			return l, false, os.Remove(expired)
+		}
+		e = os.Remove(expired)
+		if nil != e {
+			return l, false, e
+		}
+	}
+	l.attempts = current.attempts + 1
+	leased, e = consumerLeaseCreate(path, l)
+	return l, leased, e
+}
+
+func (c *ConsumerDir) acked(name string) bool {
+	_, e := os.Stat(filepath.Join(c.acks, name))
+	return nil == e
+}
+
+// Pull gets up to n records which are not acked by the group(ordered by name).
+func (c *ConsumerDir) Pull(n int, visibility time.Duration) ([]Delivery, error) {
+	entries, e := os.ReadDir(c.records)
+	if nil != e {
+		return nil, e
+	}
+	var names []string = make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var pulled []Delivery
+	for _, name := range names {
+		if n <= len(pulled) {
+			break
+		}
+		if c.acked(name) {
+			continue
+		}
+		l, leased, e := c.lease(name, visibility)
+		if nil != e {
+			return pulled, e
+		}
+		if !leased {
+			continue
+		}
+		if c.acked(name) {
+			// acked after the first check: an ack marker is written before its lease is removed
+			_ = os.Remove(filepath.Join(c.leases, name)) // the lease is garbage
+			continue
+		}
+		data, e := os.ReadFile(filepath.Join(c.records, name))
+		if errors.Is(e, fs.ErrNotExist) {
+			_ = os.Remove(filepath.Join(c.leases, name)) // the record was removed; the lease is garbage
+			continue
+		}
+		if nil != e {
+			return pulled, e
+		}
+		pulled = append(pulled, Delivery{
+			Name:     name,
+			Data:     data,
+			Attempts: l.attempts,
+			Deadline: time.Unix(0, l.deadline),
+			token:    l.token,
+		})
+	}
+	return pulled, nil
+}
+
+// owned checks that the lease of a delivery was not taken over.
+func (c *ConsumerDir) owned(d Delivery) (consumerLease, error) {
+	l, e := consumerLeaseRead(filepath.Join(c.leases, d.Name))
+	if errors.Is(e, fs.ErrNotExist) || (nil == e && l.token != d.token) {
+		return l, fmt.Errorf("%w: %s", ErrClaimLost, d.Name)
+	}
+	return l, e
+}
+
+// Ack writes an ack marker and removes the lease.
+func (c *ConsumerDir) Ack(d Delivery) error {
+	_, e := c.owned(d)
+	if nil != e {
+		return e
+	}
+	e = os.WriteFile(filepath.Join(c.acks, d.Name), nil, 0644)
+	if nil != e {
+		return e
+	}
+	return os.Remove(filepath.Join(c.leases, d.Name))
+}
+
+// Nack expires the lease; the number of attempts is kept.
+func (c *ConsumerDir) Nack(d Delivery) error {
+	l, e := c.owned(d)
+	if nil != e {
+		return e
+	}
+	l.deadline = 0
+	var tmp string = filepath.Join(c.leases, "."+d.Name+"."+d.token)
+	e = os.WriteFile(tmp, l.bytes(), 0644)
+	if nil != e {
+		return e
+	}
+	return os.Rename(tmp, filepath.Join(c.leases, d.Name))
+}
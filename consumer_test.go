@@ -0,0 +1,148 @@
+package saver_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestConsumer(t *testing.T) {
+	t.Parallel()
+
+	var recordsNew func(t *testing.T, names ...string) (records string, state string) = func(
+		t *testing.T,
+		names ...string,
+	) (records string, state string) {
+		var dir string = t.TempDir()
+		records, state = filepath.Join(dir, "records"), filepath.Join(dir, "state")
+		_ = os.MkdirAll(records, 0755)
+		for _, name := range names {
+			_ = os.WriteFile(filepath.Join(records, name), []byte("data-"+name), 0644)
+		}
+		return
+	}
+
+	var consumerNew func(t *testing.T, records, state, group string, now func() time.Time) saver.Consumer = func(
+		t *testing.T,
+		records, state, group string,
+		now func() time.Time,
+	) saver.Consumer {
+		c, e := saver.ConsumerDirNew(records, state, group, now)
+		if nil != e {
+			t.Fatal(e)
+		}
+		return c
+	}
+
+	t.Run("groups", func(t *testing.T) {
+		t.Parallel()
+
+		records, state := recordsNew(t, "a", "b", "c", ".tmp")
+		var c1 saver.Consumer = consumerNew(t, records, state, "archive", time.Now)
+		var c2 saver.Consumer = consumerNew(t, records, state, "index", time.Now)
+
+		pulled, e := c1.Pull(2, time.Minute)
+		t.Run("no pull error", assertNil(e))
+		t.Run("pulled", assertEq(len(pulled), 2))
+		t.Run("ordered", assertEq(pulled[0].Name, "a"))
+		t.Run("data", assertEq(string(pulled[0].Data), "data-a"))
+		t.Run("first attempt", assertEq(pulled[0].Attempts, 1))
+
+		rest, _ := c1.Pull(10, time.Minute)
+		t.Run("hidden", assertEq(len(rest), 1))
+		t.Run("rest", assertEq(rest[0].Name, "c"))
+
+		other, _ := c2.Pull(10, time.Minute)
+		t.Run("other group gets every record", assertEq(len(other), 3))
+
+		for _, d := range append(pulled, rest...) {
+			t.Run("no ack error", assertNil(c1.Ack(d)))
+		}
+		t.Run("acked twice", assertTrue(errors.Is(c1.Ack(pulled[0]), saver.ErrClaimLost)))
+		t.Run("no nack error", assertNil(c2.Nack(other[1])))
+
+		none, _ := c1.Pull(10, 0)
+		t.Run("all acked", assertEq(len(none), 0))
+
+		again, _ := c2.Pull(10, time.Minute)
+		t.Run("nacked visible", assertEq(len(again), 1))
+		t.Run("nacked record", assertEq(again[0].Name, "b"))
+		t.Run("attempts kept", assertEq(again[0].Attempts, 2))
+	})
+
+	t.Run("visibility timeout", func(t *testing.T) {
+		t.Parallel()
+
+		records, state := recordsNew(t, "a")
+		var now time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		var c saver.Consumer = consumerNew(t, records, state, "g", func() time.Time { return now })
+
+		first, _ := c.Pull(1, time.Minute)
+		hidden, _ := c.Pull(1, time.Minute)
+		t.Run("hidden until the deadline", assertEq(len(hidden), 0))
+		now = now.Add(2 * time.Minute)
+		second, e := c.Pull(1, time.Minute)
+		t.Run("no pull error", assertNil(e))
+		t.Run("redelivered", assertEq(len(second), 1))
+		t.Run("second attempt", assertEq(second[0].Attempts, 2))
+		t.Run("stale ack", assertTrue(errors.Is(c.Ack(first[0]), saver.ErrClaimLost)))
+		t.Run("stale nack", assertTrue(errors.Is(c.Nack(first[0]), saver.ErrClaimLost)))
+		t.Run("no ack error", assertNil(c.Ack(second[0])))
+	})
+
+	t.Run("invalid group", func(t *testing.T) {
+		t.Parallel()
+
+		records, state := recordsNew(t)
+		_, e := saver.ConsumerDirNew(records, state, "../x", time.Now)
+		t.Run("rejected", assertTrue(errors.Is(e, saver.ErrInvalidGroup)))
+	})
+
+	t.Run("concurrent consumers", func(t *testing.T) {
+		t.Parallel()
+
+		var names []string
+		for i := 0; i < 40; i++ {
+			names = append(names, string(rune('A'+i)))
+		}
+		records, state := recordsNew(t, names...)
+
+		var lock sync.Mutex
+		var seen map[string]int = make(map[string]int)
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c, e := saver.ConsumerDirNew(records, state, "g", time.Now)
+				if nil != e {
+					return
+				}
+				for {
+					pulled, e := c.Pull(3, time.Minute)
+					if nil != e || 0 == len(pulled) {
+						return
+					}
+					for _, d := range pulled {
+						lock.Lock()
+						seen[d.Name]++
+						lock.Unlock()
+						_ = c.Ack(d)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		var once bool = 40 == len(seen)
+		for _, n := range seen {
+			once = once && 1 == n
+		}
+		t.Run("each record once", assertTrue(once))
+	})
+}
@@ -0,0 +1,274 @@
+package saver
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordIDGen creates a record id.
+type RecordIDGen func() (id string)
+
+// RecordIDGenRandom creates a random(128 bits) record id.
+var RecordIDGenRandom RecordIDGen = func() (id string) {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:]) // crypto/rand never fails on supported platforms
+	return hex.EncodeToString(buf[:])
+}
+
+// ResponseStd is a captured standard(net/http) response.
+type ResponseStd struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Latency time.Duration
+}
+
+// ExchangeStd is a captured standard(net/http) request and its response(nil if not captured).
+type ExchangeStd struct {
+	ID         string
+	Time       time.Time
+	Method     string
+	URI        string
+	Host       string
+	RemoteAddr string
+	Request    RequestStd
+	Response   *ResponseStd
+
+	// BodyRef refers to a request body kept in a BlobStore(nil if the body is inline).
+	BodyRef *BlobRef
+}
+
+// ExchangeStdNew creates an exchange from a request and its captured body.
+//
+// # Arguments
+//   - q: A server or client request.
+//   - body: A captured request body.
+//   - id: A record id.
+//   - received: The time the request was received(or sent).
+func ExchangeStdNew(q *http.Request, body []byte, id string, received time.Time) ExchangeStd {
+	var host string = q.Host
+	if "" == host && nil != q.URL {
+		host = q.URL.Host
+	}
+	return ExchangeStd{
+		ID:         id,
+		Time:       received,
+		Method:     q.Method,
+		URI:        q.URL.RequestURI(),
+		Host:       host,
+		RemoteAddr: q.RemoteAddr,
+		Request:    RequestStd(RequestNew(q.Header.Clone(), body)),
+		Response:   nil,
+		BodyRef:    nil,
+	}
+}
+
+// ExchangeSaver saves an exchange.
+type ExchangeSaver[R any] RequestSaver[ExchangeStd, R]
+
+// ExchangeStd2bytes must serialize an exchange as a slice of bytes.
+type ExchangeStd2bytes func(x ExchangeStd) (serialized []byte, e error)
+
+// NewExchangeSaver creates an exchange saver which saves an exchange as a slice of bytes.
+func (b BytesSaver) NewExchangeSaver(serializer ExchangeStd2bytes) ExchangeSaver[int64] {
+	return ExchangeSaver[int64](RequestSaverNew(serializer, b))
+}
+
+const (
+	exchangeMetaID     = "meta/id"
+	exchangeMetaTime   = "meta/time"
+	exchangeMetaMethod = "meta/method"
+	exchangeMetaURI    = "meta/uri"
+	exchangeMetaHost   = "meta/host"
+	exchangeMetaRemote = "meta/remote"
+	exchangeHeader     = "header/"
+	exchangeBody       = "body/body"
+	exchangeBodyRef    = "body/ref"
+	exchangeResStatus  = "response/status"
+	exchangeResLatency = "response/latency"
+	exchangeResHeader  = "response/header/"
+	exchangeResBody    = "response/body"
+	exchangeItemMode   = 0400
+)
+
+func tarWriteItem(tw *tar.Writer, name string, content []byte) error {
+	_, e := Compose(
+		func(h *tar.Header) ([]byte, error) { return content, tw.WriteHeader(h) },
+		func(body []byte) (int, error) { return tw.Write(body) },
+	)(&tar.Header{
+		Name: name,
+		Mode: exchangeItemMode,
+		Size: int64(len(content)),
+	})
+	return e
+}
+
+func tarWriteHeader(tw *tar.Writer, prefix string, h http.Header) error {
+	var keys []string = make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, val := range h[key] {
+			var e error = tarWriteItem(tw, prefix+key, []byte(val))
+			if nil != e {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// ExchangeStd2bytesTar serializes an exchange as a tar archive.
+//
+// The layout extends the layout of RequestSerializerNewGenericTar:
+//   - meta/id, meta/time, meta/method, meta/uri, meta/host, meta/remote(omitted if empty)
+//   - header/{key}: A request header value(keys are sorted).
+//   - body/body: A request body.
+//   - body/ref: A JSON reference of a request body kept in a BlobStore(only if spilled).
+//   - response/status, response/latency, response/header/{key}, response/body(if captured)
+var ExchangeStd2bytesTar ExchangeStd2bytes = func(x ExchangeStd) (serialized []byte, e error) {
+	var buf bytes.Buffer
+	var tw *tar.Writer = tar.NewWriter(&buf)
+
+	var timestamp string
+	if !x.Time.IsZero() {
+		timestamp = x.Time.UTC().Format(time.RFC3339Nano)
+	}
+	var metas [][2]string = [][2]string{
+		{exchangeMetaID, x.ID},
+		{exchangeMetaTime, timestamp},
+		{exchangeMetaMethod, x.Method},
+		{exchangeMetaURI, x.URI},
+		{exchangeMetaHost, x.Host},
+		{exchangeMetaRemote, x.RemoteAddr},
+	}
+	for _, meta := range metas {
+		if "" == meta[1] {
+			continue
+		}
+		e = tarWriteItem(tw, meta[0], []byte(meta[1]))
+		if nil != e {
+			return nil, e
+		}
+	}
+
+	var q Request[http.Header, []byte] = Request[http.Header, []byte](x.Request)
+	e = errors.Join(
+		tarWriteHeader(tw, exchangeHeader, q.Header()),
+		tarWriteItem(tw, exchangeBody, q.Body()),
+	)
+	if nil != e {
+		return nil, e
+	}
+	if nil != x.BodyRef {
+		ref, _ := json.Marshal(x.BodyRef) // always nil error
+		e = tarWriteItem(tw, exchangeBodyRef, ref)
+		if nil != e {
+			return nil, e
+		}
+	}
+
+	if nil != x.Response {
+		e = errors.Join(
+			tarWriteItem(tw, exchangeResStatus, []byte(strconv.Itoa(x.Response.Status))),
+			tarWriteItem(tw, exchangeResLatency, []byte(x.Response.Latency.String())),
+			tarWriteHeader(tw, exchangeResHeader, x.Response.Header),
+			tarWriteItem(tw, exchangeResBody, x.Response.Body),
+		)
+		if nil != e {
+			return nil, e
+		}
+	}
+
+	e = tw.Close()
+	return buf.Bytes(), e
+}
+
+// ExchangeStdFromTar parses a tar archive created by ExchangeStd2bytesTar.
+//
+// Archives created by RequestSerializerNewGenericTar(headers and a body only) can also be parsed.
+func ExchangeStdFromTar(serialized []byte) (x ExchangeStd, e error) {
+	return ExchangeStdReadTar(tar.NewReader(bytes.NewReader(serialized)))
+}
+
+// ExchangeStdReadTar reads an exchange from a tar reader until the end of the archive.
+func ExchangeStdReadTar(tr *tar.Reader) (x ExchangeStd, e error) {
+	var header http.Header = make(http.Header)
+	var body []byte
+	var res ResponseStd = ResponseStd{Header: make(http.Header)}
+	var hasResponse bool
+
+	for {
+		var hdr *tar.Header
+		hdr, e = tr.Next()
+		if errors.Is(e, io.EOF) {
+			break
+		}
+		if nil != e {
+			return x, e
+		}
+		var content []byte
+		content, e = io.ReadAll(tr)
+		if nil != e {
+			return x, e
+		}
+		var name string = hdr.Name
+		var val string = string(content)
+		switch {
+		case exchangeMetaID == name:
+			x.ID = val
+		case exchangeMetaTime == name:
+			x.Time, e = time.Parse(time.RFC3339Nano, val)
+		case exchangeMetaMethod == name:
+			x.Method = val
+		case exchangeMetaURI == name:
+			x.URI = val
+		case exchangeMetaHost == name:
+			x.Host = val
+		case exchangeMetaRemote == name:
+			x.RemoteAddr = val
+		case strings.HasPrefix(name, exchangeHeader):
+			var key string = strings.TrimPrefix(name, exchangeHeader)
+			header[key] = append(header[key], val)
+		case exchangeBody == name:
+			body = content
+		case exchangeBodyRef == name:
+			x.BodyRef = &BlobRef{}
+			e = json.Unmarshal(content, x.BodyRef)
+		case exchangeResStatus == name:
+			hasResponse = true
+			res.Status, e = strconv.Atoi(val)
+		case exchangeResLatency == name:
+			hasResponse = true
+			res.Latency, e = time.ParseDuration(val)
+		case strings.HasPrefix(name, exchangeResHeader):
+			hasResponse = true
+			var key string = strings.TrimPrefix(name, exchangeResHeader)
+			res.Header[key] = append(res.Header[key], val)
+		case exchangeResBody == name:
+			hasResponse = true
+			res.Body = content
+		}
+		if nil != e {
+			return x, e
+		}
+	}
+
+	x.Request = RequestStd(RequestNew(header, body))
+	if hasResponse {
+		x.Response = &res
+	}
+	return x, nil
+}
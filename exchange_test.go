@@ -0,0 +1,134 @@
+package saver_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestExchange(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ExchangeStd2bytesTar", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("round trip", func(t *testing.T) {
+			t.Parallel()
+
+			var q *http.Request = httptest.NewRequest("POST", "/api/v1/write?db=x", nil)
+			q.Header.Set("Content-Type", "application/json")
+			var received time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+
+			var x saver.ExchangeStd = saver.ExchangeStdNew(q, []byte(`{}`), "id-42", received)
+			x.Response = &saver.ResponseStd{
+				Status:  201,
+				Header:  http.Header{"Location": {"/items/42"}},
+				Body:    []byte("created"),
+				Latency: 3 * time.Millisecond,
+			}
+
+			serialized, e := saver.ExchangeStd2bytesTar(x)
+			t.Run("no serialize error", assertNil(e))
+
+			parsed, e := saver.ExchangeStdFromTar(serialized)
+			t.Run("no parse error", assertNil(e))
+			t.Run("id", assertEq(parsed.ID, "id-42"))
+			t.Run("time", assertTrue(received.Equal(parsed.Time)))
+			t.Run("method", assertEq(parsed.Method, "POST"))
+			t.Run("uri", assertEq(parsed.URI, "/api/v1/write?db=x"))
+			t.Run("host", assertEq(parsed.Host, "example.com"))
+
+			var req saver.Request[http.Header, []byte] = saver.Request[http.Header, []byte](parsed.Request)
+			t.Run("header", assertEq(req.Header().Get("Content-Type"), "application/json"))
+			t.Run("body", assertEq(string(req.Body()), `{}`))
+
+			t.Run("response", assertTrue(nil != parsed.Response))
+			t.Run("status", assertEq(parsed.Response.Status, 201))
+			t.Run("latency", assertEq(parsed.Response.Latency, 3*time.Millisecond))
+			t.Run("location", assertEq(parsed.Response.Header.Get("Location"), "/items/42"))
+			t.Run("response body", assertEq(string(parsed.Response.Body), "created"))
+		})
+
+		t.Run("request only layout", func(t *testing.T) {
+			t.Parallel()
+
+			var ts saver.RequestSerializer[
+				[]byte, http.Header, []byte,
+			] = saver.RequestSerializerNewGenericTar(
+				func(h http.Header, user func(key, val []byte)) {
+					for key, values := range h {
+						for _, val := range values {
+							user([]byte(key), []byte(val))
+						}
+					}
+				},
+				func(key []byte) string { return string(key) },
+				func(body []byte) []byte { return body },
+				func(e error) { panic(e) },
+			)
+			serialized, e := ts(saver.RequestNew(http.Header{"X-Id": {"1"}}, []byte("hw")))
+			t.Run("no serialize error", assertNil(e))
+
+			parsed, e := saver.ExchangeStdFromTar(serialized)
+			t.Run("no parse error", assertNil(e))
+			var req saver.Request[http.Header, []byte] = saver.Request[http.Header, []byte](parsed.Request)
+			t.Run("header", assertEq(req.Header().Get("X-Id"), "1"))
+			t.Run("body", assertEq(string(req.Body()), "hw"))
+			t.Run("no response", assertTrue(nil == parsed.Response))
+		})
+	})
+
+	t.Run("ExchangeSaver", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("ToMiddleware", func(t *testing.T) {
+			t.Parallel()
+
+			var saved []byte
+			var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+				saved = bytes.Clone(serialized)
+				return int64(len(saved)), nil
+			}
+			var mw func(http.Handler) http.Handler = sav.NewExchangeSaver(
+				saver.ExchangeStd2bytesTar,
+			).ToMiddleware(1024, 4, saver.RecordIDGenRandom, func(e error, _ *http.Request) { panic(e) })
+
+			var handler http.Handler = mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(418)
+				_, _ = w.Write([]byte("teapot"))
+			}))
+
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/hook", strings.NewReader("hw")))
+			t.Run("served", assertEq(w.Body.String(), "teapot"))
+
+			var tr *tar.Reader = tar.NewReader(bytes.NewReader(saved))
+			var names []string
+			for {
+				hdr, e := tr.Next()
+				if nil != e {
+					break
+				}
+				names = append(names, hdr.Name)
+			}
+			t.Run("response namespace", assertTrue(strings.Contains(
+				strings.Join(names, ","),
+				"response/status",
+			)))
+
+			parsed, e := saver.ExchangeStdFromTar(saved)
+			t.Run("no parse error", assertNil(e))
+			t.Run("id", assertEq(len(parsed.ID), 32))
+			t.Run("status", assertEq(parsed.Response.Status, 418))
+			t.Run("limited body", assertEq(string(parsed.Response.Body), "teap"))
+			t.Run("content type", assertEq(parsed.Response.Header.Get("Content-Type"), "text/plain"))
+		})
+	})
+}
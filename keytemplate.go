@@ -0,0 +1,384 @@
+package saver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrKeyValueMissing is returned when a value of a key template is missing and there is no default.
+var ErrKeyValueMissing error = errors.New("missing key value")
+
+// KeyTemplate gets a key of an exchange.
+type KeyTemplate func(x ExchangeStd) (key string, e error)
+
+// keyItem gets a part of a key.
+type keyItem func(x *ExchangeStd) (part string, e error)
+
+func keyItemLiteral(literal string) keyItem {
+	return func(_ *ExchangeStd) (string, error) { return literal, nil }
+}
+
+// keyItemOr uses a default value if a value is missing.
+func keyItemOr(placeholder, def string, hasDefault bool, get func(x *ExchangeStd) (string, bool)) keyItem {
+	return func(x *ExchangeStd) (string, error) {
+		val, found := get(x)
+		switch {
+		case found:
+			return val, nil
+		case hasDefault:
+			return def, nil
+		default:
+			return "", fmt.Errorf("%w: {%s}", ErrKeyValueMissing, placeholder)
+		}
+	}
+}
+
+func keyBody(x *ExchangeStd) []byte { return Request[http.Header, []byte](x.Request).Body() }
+
+// jsonPointerParse gets reference tokens of a JSON Pointer(RFC 6901).
+func jsonPointerParse(pointer string) ([]string, error) {
+	if "" == pointer {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer(must start with /): %q", pointer)
+	}
+	var tokens []string = strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(token), "~") {
+			return nil, fmt.Errorf("invalid escape in JSON pointer: %q", pointer)
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// jsonPointerGet gets a value of a JSON document as a string.
+func jsonPointerGet(document []byte, tokens []string) (string, bool) {
+	var dec *json.Decoder = json.NewDecoder(bytes.NewReader(document))
+	dec.UseNumber()
+	var current any
+	if nil != dec.Decode(&current) {
+		return "", false
+	}
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]any:
+			child, found := node[token]
+			if !found {
+				return "", false
+			}
+			current = child
+		case []any:
+			i, e := strconv.Atoi(token)
+			if nil != e || i < 0 || len(node) <= i {
+				return "", false
+			}
+			current = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch val := current.(type) {
+	case nil:
+		return "", false
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	default:
+		serialized, _ := json.Marshal(val) // always nil error(decoded values)
+		return string(serialized), true
+	}
+}
+
+// keyEnv is used by placeholders.
+type keyEnv struct {
+	now func() time.Time
+	seq Sequence // nil: a counter of a process
+}
+
+// keyItemMetaNew creates a placeholder of metadata; an empty value is missing.
+func keyItemMetaNew(name string, get func(x *ExchangeStd) string) func(string, keyEnv) (keyItem, error) {
+	return func(arg string, _ keyEnv) (keyItem, error) {
+		def, hasDefault := strings.CutPrefix(arg, "|")
+		if "" != arg && !hasDefault {
+			return nil, fmt.Errorf("{%s} has no argument: %q", name, arg)
+		}
+		return keyItemOr(name+arg, def, hasDefault, func(x *ExchangeStd) (string, bool) {
+			var val string = get(x)
+			return val, "" != val
+		}), nil
+	}
+}
+
+func keyItemEscaped(item keyItem, escape func(value string) string) keyItem {
+	return func(x *ExchangeStd) (string, error) {
+		val, e := item(x)
+		return escape(val), e
+	}
+}
+
+// keyPlaceholders creates items from a placeholder argument.
+var keyPlaceholders map[string]func(arg string, env keyEnv) (keyItem, error) = map[string]func(
+	arg string,
+	env keyEnv,
+) (keyItem, error){
+	"header": func(arg string, _ keyEnv) (keyItem, error) {
+		name, def, hasDefault := strings.Cut(arg, "|")
+		if "" == name {
+			return nil, errors.New("{header:NAME} requires a header name")
+		}
+		return keyItemOr("header:"+arg, def, hasDefault, func(x *ExchangeStd) (string, bool) {
+			var values []string = Request[http.Header, []byte](x.Request).Header().Values(name)
+			if 0 == len(values) {
+				return "", false
+			}
+			return values[0], true
+		}), nil
+	},
+	"method": keyItemMetaNew("method", func(x *ExchangeStd) string { return x.Method }),
+	"host":   keyItemMetaNew("host", func(x *ExchangeStd) string { return x.Host }),
+	"id":     keyItemMetaNew("id", func(x *ExchangeStd) string { return x.ID }),
+	"path": func(arg string, _ keyEnv) (keyItem, error) {
+		index, def, hasDefault := strings.Cut(arg, "|")
+		if "" == index {
+			return keyItemOr("path"+arg, def, hasDefault, func(x *ExchangeStd) (string, bool) {
+				path, _, _ := strings.Cut(x.URI, "?")
+				unescaped, e := url.PathUnescape(path)
+				return unescaped, "" != path && nil == e
+			}), nil
+		}
+		i, e := strconv.Atoi(index)
+		if nil != e || i < 0 {
+			return nil, fmt.Errorf("{path:N} requires a segment index(0-based): %q", arg)
+		}
+		return keyItemOr("path:"+arg, def, hasDefault, func(x *ExchangeStd) (string, bool) {
+			path, _, _ := strings.Cut(x.URI, "?")
+			var segments []string
+			for _, segment := range strings.Split(path, "/") {
+				if "" != segment {
+					segments = append(segments, segment)
+				}
+			}
+			if len(segments) <= i {
+				return "", false
+			}
+			unescaped, e := url.PathUnescape(segments[i])
+			return unescaped, nil == e
+		}), nil
+	},
+	"query": func(arg string, _ keyEnv) (keyItem, error) {
+		name, def, hasDefault := strings.Cut(arg, "|")
+		if "" == name {
+			return nil, errors.New("{query:NAME} requires a parameter name")
+		}
+		return keyItemOr("query:"+arg, def, hasDefault, func(x *ExchangeStd) (string, bool) {
+			_, rawQuery, _ := strings.Cut(x.URI, "?")
+			query, _ := url.ParseQuery(rawQuery) // valid pairs are kept
+			if !query.Has(name) {
+				return "", false
+			}
+			return query.Get(name), true
+		}), nil
+	},
+	"json": func(arg string, _ keyEnv) (keyItem, error) {
+		pointer, def, hasDefault := strings.Cut(arg, "|")
+		tokens, e := jsonPointerParse(pointer)
+		if nil != e {
+			return nil, e
+		}
+		return keyItemOr("json:"+arg, def, hasDefault, func(x *ExchangeStd) (string, bool) {
+			return jsonPointerGet(keyBody(x), tokens)
+		}), nil
+	},
+	"time": func(arg string, env keyEnv) (keyItem, error) {
+		var layout string = arg
+		if "" == layout {
+			layout = time.RFC3339
+		}
+		return func(x *ExchangeStd) (string, error) {
+			var t time.Time = x.Time
+			if t.IsZero() {
+				t = env.now()
+			}
+			return t.UTC().Format(layout), nil
+		}, nil
+	},
+	"seq": func(arg string, env keyEnv) (keyItem, error) {
+		var width int = 0
+		if "" != arg {
+			w, e := strconv.Atoi(arg)
+			if nil != e || w < 0 || 20 < w {
+				return nil, fmt.Errorf("{seq:WIDTH} requires a width(0-20): %q", arg)
+			}
+			width = w
+		}
+		if nil != env.seq {
+			return func(_ *ExchangeStd) (string, error) {
+				n, e := env.seq()
+				return fmt.Sprintf("%0*d", width, n), e
+			}, nil
+		}
+		var seq atomic.Uint64
+		return func(_ *ExchangeStd) (string, error) {
+			return fmt.Sprintf("%0*d", width, seq.Add(1)), nil
+		}, nil
+	},
+	"sha256": func(arg string, _ keyEnv) (keyItem, error) {
+		var size int = 2 * sha256.Size
+		if "" != arg {
+			s, e := strconv.Atoi(arg)
+			if nil != e || s < 1 || 2*sha256.Size < s {
+				return nil, fmt.Errorf("{sha256:N} requires a number of hex digits(1-64): %q", arg)
+			}
+			size = s
+		}
+		return func(x *ExchangeStd) (string, error) {
+			var digest [sha256.Size]byte = sha256.Sum256(keyBody(x))
+			return hex.EncodeToString(digest[:])[:size], nil
+		}, nil
+	},
+}
+
+// KeyTemplateNew creates a key template.
+//
+// Placeholders(a value after "|" is used if the value is missing):
+//   - {method}, {host}, {id}: Metadata of an exchange(sample: {id|none}).
+//   - {header:NAME}, {header:NAME|default}: The first value of a request header.
+//   - {path}: The request path without the query.
+//   - {path:N}: The N-th(0-based) segment of the request path.
+//   - {query:NAME}: The first value of a query parameter.
+//   - {json:/a/0/b}: A value of the body(JSON) located by a JSON Pointer(RFC 6901).
+//   - {time}, {time:LAYOUT}: The received time(UTC) formatted by a Go layout(default: RFC 3339).
+//   - {seq}, {seq:WIDTH}: A sequence number starting at 1(zero padded); unique within a process only
+//     (restarts at 1), use KeyTemplateSequenceNew for keys of a persistent store.
+//   - {sha256}, {sha256:N}: The hex encoded hash(or its first N digits) of the body.
+//
+// An invalid template(unknown placeholder, bad argument) is an error.
+//
+// # Arguments
+//   - template: A key template(sample: "req/{header:X-Tenant|none}/{time:20060102}/{id}").
+//   - now: Gets the current time if the received time is unknown.
+func KeyTemplateNew(template string, now func() time.Time) (KeyTemplate, error) {
+	return keyTemplateNew(template, keyEnv{now: now}, nil)
+}
+
+// KeyTemplateSequenceNew creates a key template whose {seq} persists across restarts.
+//
+// A number which can not be reserved is an error of the key(ErrSequenceUnavailable).
+//
+// # Arguments
+//   - template: A key template(sample: "req/{header:X-Tenant|none}/{time:20060102}/{seq:8}").
+//   - now: Gets the current time if the received time is unknown.
+//   - seq: Gets numbers of {seq}(sample: SequenceNew).
+func KeyTemplateSequenceNew(template string, now func() time.Time, seq Sequence) (KeyTemplate, error) {
+	return keyTemplateNew(template, keyEnv{now: now, seq: seq}, nil)
+}
+
+// KeyTemplateEscapedNew creates a key template which escapes values of placeholders(literals are kept).
+//
+// # Arguments
+//   - template: A key template(see KeyTemplateNew).
+//   - now: Gets the current time if the received time is unknown.
+//   - escape: Escapes a value(sample: a sanitizer of subject tokens; nil: no escape).
+func KeyTemplateEscapedNew(template string, now func() time.Time, escape func(value string) string) (KeyTemplate, error) {
+	return keyTemplateNew(template, keyEnv{now: now}, escape)
+}
+
+func keyTemplateNew(template string, env keyEnv, escape func(value string) string) (KeyTemplate, error) {
+	var items []keyItem
+	var rest string = template
+	for 0 < len(rest) {
+		before, after, found := strings.Cut(rest, "{")
+		if 0 < len(before) {
+			if strings.Contains(before, "}") {
+				return nil, fmt.Errorf("unopened placeholder in key template: %s", template)
+			}
+			items = append(items, keyItemLiteral(before))
+		}
+		if !found {
+			break
+		}
+		placeholder, next, closed := strings.Cut(after, "}")
+		if !closed {
+			return nil, fmt.Errorf("unclosed placeholder in key template: %s", template)
+		}
+		var name string = placeholder
+		var arg string
+		if i := strings.IndexAny(placeholder, ":|"); 0 <= i {
+			name, arg = placeholder[:i], strings.TrimPrefix(placeholder[i:], ":")
+		}
+		itemNew, known := keyPlaceholders[name]
+		if !known {
+			return nil, fmt.Errorf("unknown placeholder in key template: {%s}", placeholder)
+		}
+		item, e := itemNew(arg, env)
+		if nil != e {
+			return nil, e
+		}
+		if nil != escape {
+			item = keyItemEscaped(item, escape)
+		}
+		items = append(items, item)
+		rest = next
+	}
+	if 0 == len(items) {
+		return nil, errors.New("empty key template")
+	}
+	return func(x ExchangeStd) (key string, e error) {
+		var b strings.Builder
+		for _, item := range items {
+			part, e := item(&x)
+			if nil != e {
+				return "", e
+			}
+			_, _ = b.WriteString(part) // always nil error
+		}
+		return b.String(), nil
+	}, nil
+}
+
+// ForSerialized creates a key func of serialized exchanges(tar archives created by ExchangeStd2bytesTar).
+//
+// A blob which is not an archive(or an archive without metadata) is keyed as an empty exchange.
+func (k KeyTemplate) ForSerialized() func(serialized []byte) (key string, e error) {
+	return func(serialized []byte) (key string, e error) {
+		x, _ := ExchangeStdFromTar(serialized) // the parsed part is used
+		return k(x)
+	}
+}
+
+// Request2KVPairNew creates a request2kvpair of RequestSaverNewKV.
+//
+// # Arguments
+//   - key: Gets a key(sample: KeyTemplateNew).
+//   - serializer: Gets a value(sample: ExchangeStd2bytesTar).
+//   - pairNew: Creates a key/value pair of a store.
+func Request2KVPairNew[P any](
+	key KeyTemplate,
+	serializer ExchangeStd2bytes,
+	pairNew func(key string, serialized []byte) P,
+) func(x ExchangeStd) (kvpair P, e error) {
+	return func(x ExchangeStd) (kvpair P, e error) {
+		k, e := key(x)
+		if nil != e {
+			return kvpair, e
+		}
+		serialized, e := serializer(x)
+		if nil != e {
+			return kvpair, e
+		}
+		return pairNew(k, serialized), nil
+	}
+}
@@ -0,0 +1,173 @@
+package saver_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestKeyTemplate(t *testing.T) {
+	t.Parallel()
+
+	var received time.Time = time.Date(2026, 10, 17, 13, 4, 5, 0, time.UTC)
+	var now func() time.Time = func() time.Time { return received }
+	var exchangeNew func(body string) saver.ExchangeStd = func(body string) saver.ExchangeStd {
+		var q = httptest.NewRequest("POST", "/v1/items/a%20b?user=u1&user=u2", strings.NewReader(body))
+		q.Header.Set("X-Tenant", "t1")
+		return saver.ExchangeStdNew(q, []byte(body), "id-1", received)
+	}
+
+	t.Run("values", func(t *testing.T) {
+		t.Parallel()
+
+		var body string = `{"order":{"id":12345678901234567890,"lines":[{"sku":"s/1"}],"tag":null}}`
+		var tests []struct {
+			template string
+			key      string
+		} = []struct {
+			template string
+			key      string
+		}{
+			{"req/{header:X-Tenant}", "req/t1"},
+			{"{method} {host}{path}", "POST example.com/v1/items/a b"},
+			{"{id|none}", "id-1"},
+			{"{header:X-Missing|none}", "none"},
+			{"{path:0}.{path:2}", "v1.a b"},
+			{"{path:9|root}", "root"},
+			{"{query:user}", "u1"},
+			{"{json:/order/id}", "12345678901234567890"},
+			{"{json:/order/lines/0/sku}", "s/1"},
+			{"{json:/order/lines}", `[{"sku":"s/1"}]`},
+			{"{json:/order/tag|untagged}", "untagged"},
+			{"{time:2006/01/02}/{time}", "2026/10/17/2026-10-17T13:04:05Z"},
+			{"{sha256:8}", "f5773a07"},
+			{"{seq:4}", "0001"},
+		}
+		for _, test := range tests {
+			tmpl, e := saver.KeyTemplateNew(test.template, now)
+			t.Run(test.template+" valid", assertNil(e))
+			key, e := tmpl(exchangeNew(body))
+			t.Run(test.template+" no error", assertNil(e))
+			t.Run(test.template, assertEq(key, test.key))
+		}
+	})
+
+	t.Run("sequence", func(t *testing.T) {
+		t.Parallel()
+
+		tmpl, _ := saver.KeyTemplateNew("k{seq}", now)
+		first, _ := tmpl(exchangeNew(""))
+		second, _ := tmpl(exchangeNew(""))
+		t.Run("first", assertEq(first, "k1"))
+		t.Run("second", assertEq(second, "k2"))
+	})
+
+	t.Run("persistent sequence", func(t *testing.T) {
+		t.Parallel()
+
+		var state string = filepath.Join(t.TempDir(), "seq")
+		seq, _ := saver.SequenceNew(state, 10)
+		tmpl, e := saver.KeyTemplateSequenceNew("k{seq:3}", now, seq)
+		t.Run("no template error", assertNil(e))
+		first, _ := tmpl(exchangeNew(""))
+		t.Run("first", assertEq(first, "k001"))
+
+		restarted, _ := saver.SequenceNew(state, 10)
+		tmpl, _ = saver.KeyTemplateSequenceNew("k{seq:3}", now, restarted)
+		next, _ := tmpl(exchangeNew(""))
+		t.Run("not reused after a restart", assertEq(next, "k011"))
+
+		var unavailable saver.Sequence = func() (uint64, error) { return 0, saver.ErrSequenceUnavailable }
+		tmpl, _ = saver.KeyTemplateSequenceNew("k{seq}", now, unavailable)
+		_, e = tmpl(exchangeNew(""))
+		t.Run("unavailable", assertTrue(errors.Is(e, saver.ErrSequenceUnavailable)))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+
+		for _, template := range []string{"{header:X-Missing}", "{json:/nosuch}", "{query:nosuch}", "{path:5}", "{id}"} {
+			tmpl, _ := saver.KeyTemplateNew(template, now)
+			var x saver.ExchangeStd = exchangeNew("not json")
+			x.ID = ""
+			_, e := tmpl(x)
+			t.Run(template, assertTrue(errors.Is(e, saver.ErrKeyValueMissing)))
+		}
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		t.Parallel()
+
+		tmpl, e := saver.KeyTemplateEscapedNew("cap.{path}.{host|}", now, func(value string) string {
+			return strings.ReplaceAll(strings.Trim(value, "/"), "/", ".")
+		})
+		t.Run("valid", assertNil(e))
+		var x saver.ExchangeStd = exchangeNew("")
+		x.Host = ""
+		key, _ := tmpl(x)
+		t.Run("values only", assertEq(key, "cap.v1.items.a b."))
+
+		serialized, _ := saver.ExchangeStd2bytesTar(exchangeNew(""))
+		key, _ = tmpl.ForSerialized()(serialized)
+		t.Run("serialized", assertEq(key, "cap.v1.items.a b.example.com"))
+		_, e = tmpl.ForSerialized()([]byte("not an archive"))
+		t.Run("no path", assertTrue(errors.Is(e, saver.ErrKeyValueMissing)))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, template := range []string{
+			"",
+			"{nosuch}",
+			"{header}",
+			"{path:x}",
+			"{json:no-slash}",
+			"{json:/a~2}",
+			"{seq:x}",
+			"{sha256:65}",
+			"{header:X",
+			"{id:x}",
+			"a}b",
+		} {
+			_, e := saver.KeyTemplateNew(template, now)
+			t.Run(template, assertTrue(nil != e))
+		}
+	})
+
+	t.Run("Request2KVPairNew", func(t *testing.T) {
+		t.Parallel()
+
+		type pair struct {
+			key   string
+			value []byte
+		}
+		var saved []pair
+		tmpl, _ := saver.KeyTemplateSequenceNew(
+			"req/{header:X-Tenant}/{time:20060102}/{seq:3}",
+			now,
+			func() (uint64, error) { return 1, nil },
+		)
+		var sav saver.RequestSaver[saver.ExchangeStd, int64] = saver.RequestSaverNewKV(
+			saver.Request2KVPairNew(
+				tmpl,
+				saver.ExchangeStd2bytesTar,
+				func(key string, serialized []byte) pair { return pair{key: key, value: serialized} },
+			),
+			func(p pair) (int64, error) {
+				saved = append(saved, p)
+				return int64(len(p.value)), nil
+			},
+		)
+		_, e := sav(exchangeNew("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("key", assertEq(saved[0].key, "req/t1/20261017/001"))
+		parsed, _ := saver.ExchangeStdFromTar(saved[0].value)
+		t.Run("value", assertEq(parsed.ID, "id-1"))
+	})
+}
@@ -0,0 +1,395 @@
+// Package kvlog provides an embedded log-structured key/value store which can be used by RequestSaverNewKV.
+//
+// Records are appended to a single data file; an in-memory hash index is rebuilt when the file is opened.
+package kvlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// ErrNotFound is returned when a key does not exist.
+var ErrNotFound error = errors.New("key not found")
+
+// ErrClosed is returned when a store is closed.
+var ErrClosed error = errors.New("store closed")
+
+// ErrEmptyKey is returned when a key is empty.
+var ErrEmptyKey error = errors.New("empty key")
+
+// ErrCorrupt is returned when a record before the last record is broken(see Repair).
+var ErrCorrupt error = errors.New("corrupt record")
+
+const (
+	opPut    byte = 0
+	opDelete byte = 1
+
+	// headerSize is the size of crc32(4), header crc32(4), op(1), key length(4) and value length(4).
+	//
+	// The record crc32 covers the rest of the record; the header crc32 covers the op and the lengths.
+	headerSize = 17
+)
+
+var crcTable *crc32.Table = crc32.MakeTable(crc32.Castagnoli)
+
+// entry is the location of a value in the data file.
+type entry struct {
+	offset int64
+	size   uint32
+}
+
+// Pair is a key/value pair.
+type Pair struct {
+	Key   []byte
+	Value []byte
+}
+
+// Store is a key/value store backed by an append-only file.
+type Store struct {
+	// Sync calls fsync after each write.
+	Sync bool
+
+	lock     sync.RWMutex
+	filename string
+	file     *os.File
+	size     int64
+	index    map[string]entry
+	garbage  int64
+}
+
+// Open opens a data file(created if missing) and rebuilds the index.
+//
+// A broken tail(an interrupted write of the last record) is truncated.
+// Other broken records are not truncated(ErrCorrupt); the file is kept as is.
+func Open(filename string) (*Store, error) {
+	file, e := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if nil != e {
+		return nil, e
+	}
+	var s *Store = &Store{filename: filename, file: file}
+	e = s.load(false)
+	if nil != e {
+		return nil, errors.Join(e, file.Close())
+	}
+	return s, nil
+}
+
+// Repair truncates a data file at the first broken record; records after it are lost.
+//
+// Use it after Open returned ErrCorrupt(and a backup of the file is taken).
+//
+// # Returns
+//   - The number of removed bytes.
+func Repair(filename string) (removed int64, e error) {
+	file, e := os.OpenFile(filename, os.O_RDWR, 0644)
+	if nil != e {
+		return 0, e
+	}
+	info, e := file.Stat()
+	if nil != e {
+		return 0, errors.Join(e, file.Close())
+	}
+	var s *Store = &Store{filename: filename, file: file}
+	e = errors.Join(s.load(true), file.Close())
+	if nil != e {
+		return 0, e
+	}
+	return info.Size() - s.size, nil
+}
+
+// load reads every record and truncates a broken tail.
+//
+// # Arguments
+//   - repair: Truncates the file at a broken record instead of ErrCorrupt.
+func (s *Store) load(repair bool) error {
+	info, e := s.file.Stat()
+	if nil != e {
+		return e
+	}
+	var fileSize int64 = info.Size()
+	s.index = make(map[string]entry)
+	s.garbage = 0
+	var rdr *bufio.Reader = bufio.NewReader(io.NewSectionReader(s.file, 0, fileSize))
+	var offset int64 = 0
+	var header [headerSize]byte
+	for {
+		_, e = io.ReadFull(rdr, header[:])
+		if nil != e {
+			break // EOF or a broken header
+		}
+		if crc32.Checksum(header[8:], crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+			// the lengths are unknown; a torn write keeps the header as written
+			if repair {
+				break
+			}
+			return fmt.Errorf("%w: header at offset %d of %s", ErrCorrupt, offset, s.filename)
+		}
+		var op byte = header[8]
+		var keySize uint32 = binary.BigEndian.Uint32(header[9:13])
+		var valSize uint32 = binary.BigEndian.Uint32(header[13:17])
+		var recordSize int64 = headerSize + int64(keySize) + int64(valSize)
+		if fileSize < offset+recordSize {
+			break // the last write is torn(valid lengths past the end); nothing is allocated
+		}
+		var body []byte = make([]byte, recordSize-headerSize)
+		_, e = io.ReadFull(rdr, body)
+		if nil != e {
+			return e
+		}
+		var crc uint32 = crc32.Update(crc32.Checksum(header[4:], crcTable), crcTable, body)
+		if crc != binary.BigEndian.Uint32(header[:4]) {
+			if offset+recordSize < fileSize && !repair {
+				return fmt.Errorf("%w: offset %d of %s", ErrCorrupt, offset, s.filename)
+			}
+			break
+		}
+		var key string = string(body[:keySize])
+		old, found := s.index[key]
+		if found {
+			s.garbage += headerSize + int64(len(key)) + int64(old.size)
+		}
+		switch op {
+		case opDelete:
+			delete(s.index, key)
+			s.garbage += recordSize
+		default:
+			s.index[key] = entry{offset: offset + headerSize + int64(keySize), size: valSize}
+		}
+		offset += recordSize
+	}
+	s.size = offset
+	e = s.file.Truncate(offset)
+	if nil != e {
+		return e
+	}
+	_, e = s.file.Seek(offset, io.SeekStart)
+	return e
+}
+
+func encode(op byte, key, value []byte) []byte {
+	var record []byte = make([]byte, headerSize+len(key)+len(value))
+	record[8] = op
+	binary.BigEndian.PutUint32(record[9:13], uint32(len(key)))
+	binary.BigEndian.PutUint32(record[13:17], uint32(len(value)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(record[8:headerSize], crcTable))
+	copy(record[headerSize:], key)
+	copy(record[headerSize+len(key):], value)
+	binary.BigEndian.PutUint32(record[:4], crc32.Checksum(record[4:], crcTable))
+	return record
+}
+
+// write appends a record.
+func (s *Store) write(op byte, key, value []byte) (offset int64, e error) {
+	if nil == s.file {
+		return 0, ErrClosed
+	}
+	if 0 == len(key) {
+		return 0, ErrEmptyKey
+	}
+	var record []byte = encode(op, key, value)
+	_, e = s.file.Write(record)
+	if nil == e && s.Sync {
+		e = s.file.Sync()
+	}
+	if nil != e {
+		// drop a partially written record
+		return 0, errors.Join(e, s.file.Truncate(s.size), s.seekEnd())
+	}
+	offset = s.size
+	s.size += int64(len(record))
+	return offset, nil
+}
+
+func (s *Store) seekEnd() error {
+	_, e := s.file.Seek(s.size, io.SeekStart)
+	return e
+}
+
+// Put stores a value.
+func (s *Store) Put(key, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	offset, e := s.write(opPut, key, value)
+	if nil != e {
+		return e
+	}
+	old, found := s.index[string(key)]
+	if found {
+		s.garbage += headerSize + int64(len(key)) + int64(old.size)
+	}
+	s.index[string(key)] = entry{offset: offset + headerSize + int64(len(key)), size: uint32(len(value))}
+	return nil
+}
+
+// Delete removes a key; a missing key is not an error.
+func (s *Store) Delete(key []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	old, found := s.index[string(key)]
+	if !found {
+		return nil
+	}
+	_, e := s.write(opDelete, key, nil)
+	if nil != e {
+		return e
+	}
+	delete(s.index, string(key))
+	s.garbage += 2*headerSize + 2*int64(len(key)) + int64(old.size)
+	return nil
+}
+
+func (s *Store) read(en entry) ([]byte, error) {
+	var value []byte = make([]byte, en.size)
+	_, e := s.file.ReadAt(value, en.offset)
+	return value, e
+}
+
+// Get gets a value.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if nil == s.file {
+		return nil, ErrClosed
+	}
+	en, found := s.index[string(key)]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return s.read(en)
+}
+
+// Scan calls f for each key with the prefix(in key order).
+//
+// The store must not be modified by f.
+func (s *Store) Scan(prefix []byte, f func(key, value []byte) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if nil == s.file {
+		return ErrClosed
+	}
+	var keys []string
+	for key := range s.index {
+		if strings.HasPrefix(key, string(prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, e := s.read(s.index[key])
+		if nil != e {
+			return e
+		}
+		e = f([]byte(key), value)
+		if nil != e {
+			return e
+		}
+	}
+	return nil
+}
+
+// Len gets the number of keys.
+func (s *Store) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.index)
+}
+
+// Garbage gets the number of bytes used by overwritten or deleted records.
+func (s *Store) Garbage() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.garbage
+}
+
+// Compact rewrites live records to a new file(with fsync) and replaces the data file.
+func (s *Store) Compact() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if nil == s.file {
+		return ErrClosed
+	}
+	var tmp string = s.filename + ".compact"
+	compacted, e := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if nil != e {
+		return e
+	}
+	var wtr *bufio.Writer = bufio.NewWriter(compacted)
+	var keys []string = make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, e := s.read(s.index[key])
+		if nil == e {
+			_, e = wtr.Write(encode(opPut, []byte(key), value))
+		}
+		if nil != e {
+			return errors.Join(e, compacted.Close(), os.Remove(tmp))
+		}
+	}
+	e = wtr.Flush()
+	if nil == e {
+		e = compacted.Sync()
+	}
+	e = errors.Join(e, compacted.Close())
+	if nil == e {
+		e = os.Rename(tmp, s.filename)
+	}
+	if nil != e {
+		return errors.Join(e, os.Remove(tmp))
+	}
+
+	file, e := os.OpenFile(s.filename, os.O_RDWR, 0644)
+	if nil != e {
+		return e
+	}
+	e = s.file.Close() // the old(renamed over) file
+	s.file = file
+	return errors.Join(e, s.load(false))
+}
+
+// Close closes the data file.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if nil == s.file {
+		return nil
+	}
+	var e error = s.file.Close()
+	s.file = nil
+	return e
+}
+
+// Save stores a pair and returns the number of value bytes(a saver of RequestSaverNewKV).
+func (s *Store) Save(p Pair) (bytesCount int64, e error) {
+	return int64(len(p.Value)), s.Put(p.Key, p.Value)
+}
+
+// ExchangePairNew creates a request2kvpair of RequestSaverNewKV which uses the record id as a key.
+//
+// # Arguments
+//   - prefix: A prefix of keys(sample: "req/").
+//   - serializer: Serializes an exchange(sample: ExchangeStd2bytesTar).
+func ExchangePairNew(
+	prefix string,
+	serializer saver.ExchangeStd2bytes,
+) func(x saver.ExchangeStd) (Pair, error) {
+	return func(x saver.ExchangeStd) (Pair, error) {
+		if "" == x.ID {
+			return Pair{}, ErrEmptyKey
+		}
+		serialized, e := serializer(x)
+		return Pair{Key: []byte(prefix + x.ID), Value: serialized}, e
+	}
+}
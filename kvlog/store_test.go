@@ -0,0 +1,219 @@
+package kvlog_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+	"github.com/takanoriyanagitani/go-simple-req-saver/kvlog"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+func testOpen(t *testing.T, filename string) *kvlog.Store {
+	s, e := kvlog.Open(filename)
+	if nil != e {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func testScan(s *kvlog.Store, prefix string) string {
+	var pairs []string
+	_ = s.Scan([]byte(prefix), func(key, value []byte) error {
+		pairs = append(pairs, string(key)+"="+string(value))
+		return nil
+	})
+	return strings.Join(pairs, ",")
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("put get scan", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "kv.log")
+		var s *kvlog.Store = testOpen(t, filename)
+		t.Run("no put error", assertNil(s.Put([]byte("req/b"), []byte("2"))))
+		_ = s.Put([]byte("req/a"), []byte("1"))
+		_ = s.Put([]byte("other"), []byte("x"))
+		_ = s.Put([]byte("req/a"), []byte("1!"))
+		t.Run("empty key", assertTrue(errors.Is(s.Put(nil, nil), kvlog.ErrEmptyKey)))
+
+		value, e := s.Get([]byte("req/a"))
+		t.Run("no get error", assertNil(e))
+		t.Run("latest value", assertEq(string(value), "1!"))
+		_, e = s.Get([]byte("nosuch"))
+		t.Run("not found", assertTrue(errors.Is(e, kvlog.ErrNotFound)))
+		t.Run("scan", assertEq(testScan(s, "req/"), "req/a=1!,req/b=2"))
+
+		t.Run("no delete error", assertNil(s.Delete([]byte("req/b"))))
+		t.Run("deleted", assertEq(testScan(s, "req/"), "req/a=1!"))
+		t.Run("no close error", assertNil(s.Close()))
+
+		var reopened *kvlog.Store = testOpen(t, filename)
+		t.Run("index rebuilt", assertEq(testScan(reopened, ""), "other=x,req/a=1!"))
+		t.Run("garbage", assertTrue(0 < reopened.Garbage()))
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "kv.log")
+		var s *kvlog.Store = testOpen(t, filename)
+		for i := 0; i < 10; i++ {
+			_ = s.Put([]byte("k"), []byte(strings.Repeat("v", i)))
+		}
+		_ = s.Put([]byte("gone"), []byte("x"))
+		_ = s.Delete([]byte("gone"))
+		before, _ := os.Stat(filename)
+
+		t.Run("no compact error", assertNil(s.Compact()))
+		after, _ := os.Stat(filename)
+		t.Run("smaller", assertTrue(after.Size() < before.Size()))
+		t.Run("no garbage", assertEq(s.Garbage(), 0))
+		t.Run("kept", assertEq(testScan(s, ""), "k=vvvvvvvvv"))
+
+		t.Run("no put error", assertNil(s.Put([]byte("new"), []byte("y"))))
+		_ = s.Close()
+		var reopened *kvlog.Store = testOpen(t, filename)
+		t.Run("appended after compaction", assertEq(testScan(reopened, ""), "k=vvvvvvvvv,new=y"))
+	})
+
+	t.Run("broken tail", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "kv.log")
+		var s *kvlog.Store = testOpen(t, filename)
+		_ = s.Put([]byte("a"), []byte("1"))
+		_ = s.Put([]byte("b"), []byte("2"))
+		_ = s.Close()
+
+		info, _ := os.Stat(filename)
+		_ = os.Truncate(filename, info.Size()-1) // an interrupted write
+
+		var reopened *kvlog.Store = testOpen(t, filename)
+		t.Run("complete records", assertEq(testScan(reopened, ""), "a=1"))
+		t.Run("no put error", assertNil(reopened.Put([]byte("c"), []byte("3"))))
+		_ = reopened.Close()
+
+		var again *kvlog.Store = testOpen(t, filename)
+		t.Run("appended after truncation", assertEq(testScan(again, ""), "a=1,c=3"))
+	})
+
+	t.Run("broken lengths", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "kv.log")
+		var s *kvlog.Store = testOpen(t, filename)
+		_ = s.Put([]byte("a"), []byte("1"))
+		_ = s.Close()
+
+		// a torn write of a record which claims 8 GiB
+		var header []byte = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(header[8:], crc32.MakeTable(crc32.Castagnoli)))
+		f, _ := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+		_, _ = f.Write(append(header, "partial"...))
+		_ = f.Close()
+
+		var reopened *kvlog.Store = testOpen(t, filename)
+		t.Run("complete records", assertEq(testScan(reopened, ""), "a=1"))
+	})
+
+	t.Run("corrupt record", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "kv.log")
+		var s *kvlog.Store = testOpen(t, filename)
+		_ = s.Put([]byte("a"), []byte("1"))
+		_ = s.Put([]byte("b"), []byte("2"))
+		_ = s.Close()
+
+		content, _ := os.ReadFile(filename)
+		content[17+1] ^= 0xff // the value of the first record
+		_ = os.WriteFile(filename, content, 0644)
+
+		_, e := kvlog.Open(filename)
+		t.Run("corrupt", assertTrue(errors.Is(e, kvlog.ErrCorrupt)))
+		info, _ := os.Stat(filename)
+		t.Run("not truncated", assertEq(info.Size(), int64(len(content))))
+	})
+
+	t.Run("corrupt lengths", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "kv.log")
+		var s *kvlog.Store = testOpen(t, filename)
+		for _, key := range []string{"k0", "k1", "k2", "k3", "k4"} {
+			_ = s.Put([]byte(key), []byte("v"))
+		}
+		_ = s.Close()
+
+		content, _ := os.ReadFile(filename)
+		var recordSize int = len(content) / 5
+		content[recordSize+12] ^= 0x80 // the key length of the second record(overruns the file)
+		_ = os.WriteFile(filename, content, 0644)
+
+		_, e := kvlog.Open(filename)
+		t.Run("corrupt", assertTrue(errors.Is(e, kvlog.ErrCorrupt)))
+		info, _ := os.Stat(filename)
+		t.Run("not truncated", assertEq(info.Size(), int64(len(content))))
+
+		removed, e := kvlog.Repair(filename)
+		t.Run("no repair error", assertNil(e))
+		t.Run("removed", assertEq(removed, int64(4*recordSize)))
+		var repaired *kvlog.Store = testOpen(t, filename)
+		t.Run("records before the corruption", assertEq(testScan(repaired, ""), "k0=v"))
+	})
+
+	t.Run("RequestSaverNewKV", func(t *testing.T) {
+		t.Parallel()
+
+		var s *kvlog.Store = testOpen(t, filepath.Join(t.TempDir(), "kv.log"))
+		var sav saver.ExchangeSaver[int64] = saver.ExchangeSaver[int64](saver.RequestSaverNewKV(
+			kvlog.ExchangePairNew("req/", saver.ExchangeStd2bytesTar),
+			s.Save,
+		))
+		var x saver.ExchangeStd = saver.ExchangeStdNew(httptest.NewRequest("POST", "/", nil), []byte("hw"), "id-1", time.Now())
+		_, e := sav(x)
+		t.Run("no save error", assertNil(e))
+
+		serialized, e := s.Get([]byte("req/id-1"))
+		t.Run("found by id", assertNil(e))
+		parsed, _ := saver.ExchangeStdFromTar(serialized)
+		t.Run("exchange", assertEq(parsed.ID, "id-1"))
+
+		x.ID = ""
+		_, e = sav(x)
+		t.Run("no id", assertTrue(errors.Is(e, kvlog.ErrEmptyKey)))
+	})
+}
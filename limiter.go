@@ -0,0 +1,116 @@
+package saver
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptiveLimiter limits concurrent saves using AIMD(additive increase/multiplicative decrease).
+//
+// The limit grows slowly while saves succeed quickly and shrinks quickly when
+// saves fail or become slower than a latency threshold.
+type AdaptiveLimiter struct {
+	lock     sync.Mutex
+	limit    float64
+	inflight int
+
+	minLimit  float64
+	maxLimit  float64
+	threshold time.Duration
+	backoff   float64
+}
+
+// AdaptiveLimiterNewAIMD creates an AIMD concurrency limiter.
+//
+// # Arguments
+//   - initial: The initial concurrency limit.
+//   - minLimit: The lower bound of the limit(1 if smaller).
+//   - maxLimit: The upper bound of the limit(minLimit if smaller).
+//   - threshold: Saves slower than this are treated as overload.
+//   - backoff: Multiplies the limit on overload(sample: 0.9).
+func AdaptiveLimiterNewAIMD(
+	initial, minLimit, maxLimit int,
+	threshold time.Duration,
+	backoff float64,
+) *AdaptiveLimiter {
+	if minLimit < 1 {
+		minLimit = 1
+	}
+	if maxLimit < minLimit {
+		maxLimit = minLimit
+	}
+	var a AdaptiveLimiter = AdaptiveLimiter{
+		minLimit:  float64(minLimit),
+		maxLimit:  float64(maxLimit),
+		threshold: threshold,
+		backoff:   backoff,
+	}
+	a.limit = a.clamp(float64(initial))
+	return &a
+}
+
+func (a *AdaptiveLimiter) clamp(limit float64) float64 {
+	switch {
+	case limit < a.minLimit:
+		return a.minLimit
+	case a.maxLimit < limit:
+		return a.maxLimit
+	default:
+		return limit
+	}
+}
+
+// Limit gets the current concurrency limit.
+func (a *AdaptiveLimiter) Limit() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return int(a.limit)
+}
+
+// InFlight gets the number of running saves.
+func (a *AdaptiveLimiter) InFlight() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.inflight
+}
+
+func (a *AdaptiveLimiter) acquire() (ok bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if int(a.limit) <= a.inflight {
+		return false
+	}
+	a.inflight += 1
+	return true
+}
+
+func (a *AdaptiveLimiter) release(latency time.Duration, e error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var saturated bool = int(a.limit) <= 2*a.inflight
+	a.inflight -= 1
+	switch {
+	case nil != e || a.threshold < latency:
+		a.limit = a.clamp(a.limit * a.backoff)
+	case saturated:
+		a.limit = a.clamp(a.limit + 1.0/a.limit)
+	}
+}
+
+// RequestSaverAdaptiveNew creates a wrapper which limits concurrent saves of a request saver.
+//
+// Saves over the limit are rejected with RequestLimiterErrTooMany.
+// The latency and the error of each save are fed back to the limiter.
+func RequestSaverAdaptiveNew[Q, R any](a *AdaptiveLimiter) func(RequestSaver[Q, R]) RequestSaver[Q, R] {
+	return func(original RequestSaver[Q, R]) RequestSaver[Q, R] {
+		return func(request Q) (result R, e error) {
+			if !a.acquire() {
+				e = RequestLimiterErrTooMany
+				return
+			}
+			var started time.Time = time.Now()
+			defer func() { a.release(time.Since(started), e) }()
+			return original(request)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package saver_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("AdaptiveLimiter", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("shrink on error", func(t *testing.T) {
+			t.Parallel()
+
+			var a *saver.AdaptiveLimiter = saver.AdaptiveLimiterNewAIMD(10, 2, 20, time.Hour, 0.5)
+			var failing saver.RequestSaver[uint8, int] = func(_ uint8) (int, error) {
+				return 0, errors.New("backend down")
+			}
+			var limited saver.RequestSaver[uint8, int] = saver.RequestSaverAdaptiveNew[uint8, int](a)(failing)
+
+			_, e := limited(0)
+			t.Run("save error", assertTrue(nil != e))
+			t.Run("halved", assertEq(a.Limit(), 5))
+
+			_, _ = limited(0)
+			_, _ = limited(0)
+			t.Run("min limit", assertEq(a.Limit(), 2))
+			t.Run("released", assertEq(a.InFlight(), 0))
+		})
+
+		t.Run("grow on success", func(t *testing.T) {
+			t.Parallel()
+
+			var a *saver.AdaptiveLimiter = saver.AdaptiveLimiterNewAIMD(1, 1, 3, time.Hour, 0.5)
+			var ok saver.RequestSaver[uint8, int] = func(_ uint8) (int, error) { return 1, nil }
+			var limited saver.RequestSaver[uint8, int] = saver.RequestSaverAdaptiveNew[uint8, int](a)(ok)
+
+			for i := 0; i < 16; i++ {
+				_, e := limited(0)
+				t.Run("no error", assertNil(e))
+			}
+			t.Run("max limit", assertEq(a.Limit(), 3))
+		})
+
+		t.Run("reject over limit", func(t *testing.T) {
+			t.Parallel()
+
+			var a *saver.AdaptiveLimiter = saver.AdaptiveLimiterNewAIMD(1, 1, 1, time.Hour, 0.5)
+			var inner saver.RequestSaver[uint8, int]
+			var limited saver.RequestSaver[uint8, int] = saver.RequestSaverAdaptiveNew[uint8, int](a)(
+				func(q uint8) (int, error) { return inner(q) },
+			)
+			inner = func(_ uint8) (int, error) {
+				_, e := limited(0)
+				return 0, e
+			}
+
+			_, e := limited(0)
+			t.Run("too many", assertTrue(errors.Is(e, saver.RequestLimiterErrTooMany)))
+		})
+	})
+}
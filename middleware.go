@@ -0,0 +1,230 @@
+package saver
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SaveFailurePolicy decides what a middleware does when a save fails.
+type SaveFailurePolicy uint8
+
+const (
+	// SaveFailureBlock rejects a request; the next handler is not called.
+	SaveFailureBlock SaveFailurePolicy = iota
+
+	// SaveFailureLog reports an error and calls the next handler.
+	SaveFailureLog
+)
+
+type bodyRestored struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyCapture reads up to limit bytes of a request body and restores the body.
+//
+// The restored body contains the whole original body(captured bytes + unread bytes).
+func bodyCapture(q *http.Request, limit int64) (captured []byte, e error) {
+	if nil == q.Body || http.NoBody == q.Body {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	_, e = io.Copy(&buf, io.LimitReader(q.Body, limit))
+	captured = buf.Bytes()
+	q.Body = bodyRestored{
+		Reader: io.MultiReader(bytes.NewReader(captured), q.Body),
+		Closer: q.Body,
+	}
+	return captured, e
+}
+
+// requestWithBody creates a shallow copy of a request which has a captured body.
+func requestWithBody(q *http.Request, captured []byte) *http.Request {
+	var cp *http.Request = q.WithContext(q.Context())
+	cp.Body = io.NopCloser(bytes.NewReader(captured))
+	cp.ContentLength = int64(len(captured))
+	return cp
+}
+
+// ToMiddleware creates a middleware which saves a request and then calls the next handler.
+//
+// The next handler gets the same body bytes.
+//
+// # Arguments
+//   - limit: Number of body bytes to save(resource limit).
+//   - policy: Blocks or logs save failures.
+//   - onError: Reports a save failure.
+func (s RequestSaverStd[R]) ToMiddleware(
+	limit int64,
+	policy SaveFailurePolicy,
+	onError func(e error, q *http.Request),
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+			captured, e := bodyCapture(q, limit)
+			if nil == e {
+				_, e = s(requestWithBody(q, captured))
+			}
+			if nil != e {
+				onError(e, q)
+				if SaveFailureBlock == policy {
+					status, code := ErrorRulesDefault.Lookup(e)
+					http.Error(w, code, status)
+					return
+				}
+			}
+			next.ServeHTTP(w, q)
+		})
+	}
+}
+
+// responseCapture records a response written by a handler.
+type responseCapture struct {
+	http.ResponseWriter
+	limit       int64
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func responseCaptureNew(w http.ResponseWriter, limit int64) *responseCapture {
+	return &responseCapture{ResponseWriter: w, limit: limit}
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if !c.wroteHeader && http.StatusOK <= status {
+		c.wroteHeader = true
+		c.status = status
+		c.header = c.ResponseWriter.Header().Clone()
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	var rest int64 = c.limit - int64(c.body.Len())
+	switch {
+	case rest <= 0:
+	case int64(len(p)) <= rest:
+		_, _ = c.body.Write(p) // always nil error
+	default:
+		_, _ = c.body.Write(p[:rest]) // always nil error
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush keeps handlers which assert http.Flusher working.
+func (c *responseCapture) Flush() {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(c.ResponseWriter).Flush() // ignore unsupported flush
+}
+
+// Unwrap is used by http.ResponseController.
+func (c *responseCapture) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+func (c *responseCapture) response(latency time.Duration) *ResponseStd {
+	if !c.wroteHeader {
+		c.status = http.StatusOK
+		c.header = c.ResponseWriter.Header().Clone()
+	}
+	return &ResponseStd{
+		Status:  c.status,
+		Header:  c.header,
+		Body:    c.body.Bytes(),
+		Latency: latency,
+	}
+}
+
+// ToMiddleware creates a middleware which saves a request and its response.
+//
+// The exchange is saved after the next handler returned;
+// a save failure can only be reported.
+//
+// # Arguments
+//   - requestLimit: Number of request body bytes to save.
+//   - responseLimit: Number of response body bytes to save.
+//   - idGen: Creates a record id.
+//   - onError: Reports a save failure.
+func (s ExchangeSaver[R]) ToMiddleware(
+	requestLimit, responseLimit int64,
+	idGen RecordIDGen,
+	onError func(e error, q *http.Request),
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+			var received time.Time = time.Now()
+			captured, e := bodyCapture(q, requestLimit)
+			if nil != e {
+				onError(e, q)
+			}
+			var x ExchangeStd = ExchangeStdNew(q, captured, idGen(), received)
+
+			var rc *responseCapture = responseCaptureNew(w, responseLimit)
+			next.ServeHTTP(rc, q)
+			x.Response = rc.response(time.Since(received))
+
+			_, e = s(x)
+			if nil != e {
+				onError(e, q)
+			}
+		})
+	}
+}
+
+// ToFailureMiddleware creates a middleware which saves a request only when the next handler fails.
+//
+// A request is saved when the next handler responds 5xx, panics or is slower than a threshold.
+// A panic is raised again after the request is saved.
+//
+// # Arguments
+//   - limit: Number of body bytes to buffer(resource limit).
+//   - threshold: Saves requests slower than this(0 disables the latency check).
+//   - onError: Reports a save failure.
+func (s RequestSaverStd[R]) ToFailureMiddleware(
+	limit int64,
+	threshold time.Duration,
+	onError func(e error, q *http.Request),
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+			var started time.Time = time.Now()
+			captured, e := bodyCapture(q, limit)
+			if nil != e {
+				onError(e, q)
+			}
+			var original *http.Request = requestWithBody(q, captured)
+			original.Header = q.Header.Clone()
+
+			var save func() = func() {
+				_, e := s(original)
+				if nil != e {
+					onError(e, q)
+				}
+			}
+
+			var rc *responseCapture = responseCaptureNew(w, 0)
+			defer func() {
+				var recovered any = recover()
+				if nil != recovered {
+					save()
+					panic(recovered)
+				}
+			}()
+			next.ServeHTTP(rc, q)
+
+			var latency time.Duration = time.Since(started)
+			var failed bool = http.StatusInternalServerError <= rc.response(latency).Status
+			var slow bool = 0 < threshold && threshold < latency
+			if failed || slow {
+				save()
+			}
+		})
+	}
+}
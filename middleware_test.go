@@ -0,0 +1,153 @@
+package saver_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var echo http.Handler = http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+		_, _ = io.Copy(w, q.Body)
+	})
+
+	t.Run("ToMiddleware", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("pass through", func(t *testing.T) {
+			t.Parallel()
+
+			var saved []byte
+			var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+				saved = bytes.Clone(serialized)
+				return int64(len(saved)), nil
+			}
+			var mw func(http.Handler) http.Handler = sav.NewRequestSaverStd(
+				saver.DupStdRequestSerializerNew(),
+			).ToMiddleware(4, saver.SaveFailureBlock, func(_ error, _ *http.Request) {})
+
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			mw(echo).ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("hello")))
+
+			t.Run("saved up to limit", assertEq(string(saved), "hell"))
+			t.Run("whole body restored", assertEq(w.Body.String(), "hello"))
+		})
+
+		t.Run("block", func(t *testing.T) {
+			t.Parallel()
+
+			var sav saver.BytesSaver = func(_ []byte) (int64, error) {
+				return 0, saver.RequestLimiterErrTooMany
+			}
+			var reported error
+			var mw func(http.Handler) http.Handler = sav.NewRequestSaverStd(
+				saver.DupStdRequestSerializerNew(),
+			).ToMiddleware(16, saver.SaveFailureBlock, func(e error, _ *http.Request) { reported = e })
+
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			mw(echo).ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("hw")))
+
+			t.Run("reported", assertTrue(errors.Is(reported, saver.RequestLimiterErrTooMany)))
+			t.Run("rejected", assertEq(w.Code, 429))
+		})
+
+		t.Run("log", func(t *testing.T) {
+			t.Parallel()
+
+			var sav saver.BytesSaver = func(_ []byte) (int64, error) {
+				return 0, errors.New("backend down")
+			}
+			var reported error
+			var mw func(http.Handler) http.Handler = sav.NewRequestSaverStd(
+				saver.DupStdRequestSerializerNew(),
+			).ToMiddleware(16, saver.SaveFailureLog, func(e error, _ *http.Request) { reported = e })
+
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			mw(echo).ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("hw")))
+
+			t.Run("reported", assertTrue(nil != reported))
+			t.Run("served", assertEq(w.Body.String(), "hw"))
+		})
+	})
+
+	t.Run("ToFailureMiddleware", func(t *testing.T) {
+		t.Parallel()
+
+		var newMiddleware func(saved *[]string) func(http.Handler) http.Handler = func(
+			saved *[]string,
+		) func(http.Handler) http.Handler {
+			var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+				*saved = append(*saved, string(serialized))
+				return int64(len(serialized)), nil
+			}
+			return sav.NewRequestSaverStd(
+				saver.DupStdRequestSerializerNew(),
+			).ToFailureMiddleware(1024, 50*time.Millisecond, func(e error, _ *http.Request) { panic(e) })
+		}
+
+		t.Run("success not saved", func(t *testing.T) {
+			t.Parallel()
+
+			var saved []string
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			newMiddleware(&saved)(echo).ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("ok")))
+			t.Run("served", assertEq(w.Body.String(), "ok"))
+			t.Run("not saved", assertEq(len(saved), 0))
+		})
+
+		t.Run("5xx saved", func(t *testing.T) {
+			t.Parallel()
+
+			var saved []string
+			var failing http.Handler = http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+				_, _ = io.Copy(io.Discard, q.Body)
+				w.WriteHeader(503)
+			})
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			newMiddleware(&saved)(failing).ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("ng")))
+			t.Run("status", assertEq(w.Code, 503))
+			t.Run("saved", assertEq(len(saved), 1))
+			t.Run("whole body", assertEq(saved[0], "ng"))
+		})
+
+		t.Run("slow saved", func(t *testing.T) {
+			t.Parallel()
+
+			var saved []string
+			var slow http.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			})
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			newMiddleware(&saved)(slow).ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("zz")))
+			t.Run("saved", assertEq(len(saved), 1))
+		})
+
+		t.Run("panic saved", func(t *testing.T) {
+			t.Parallel()
+
+			var saved []string
+			var panicking http.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				panic("boom")
+			})
+			var recovered any
+			func() {
+				defer func() { recovered = recover() }()
+				newMiddleware(&saved)(panicking).ServeHTTP(
+					httptest.NewRecorder(),
+					httptest.NewRequest("POST", "/", strings.NewReader("pp")),
+				)
+			}()
+			t.Run("panic again", assertEq(recovered, any("boom")))
+			t.Run("saved", assertEq(len(saved), 1))
+		})
+	})
+}
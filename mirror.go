@@ -0,0 +1,131 @@
+package saver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ShadowMirror forwards copies of saved requests to shadow upstreams.
+//
+// Mirrors run in background goroutines; their failures never affect the primary response.
+type ShadowMirror struct {
+	// Client sends copies.
+	Client *http.Client
+
+	targets    []*url.URL
+	sampleRate float64
+	timeout    time.Duration
+	limit      int64
+	onError    func(target *url.URL, e error)
+	sem        chan struct{}
+	running    sync.WaitGroup
+}
+
+// ShadowMirrorNew creates a shadow mirror which uses the default client.
+//
+// # Arguments
+//   - targets: Base urls of shadow upstreams.
+//   - sampleRate: Ratio of requests to mirror(0.0 - 1.0).
+//   - timeout: Timeout of each mirrored request(0: no timeout).
+//   - concurrency: Max number of running mirrored requests(at least 1; more copies are dropped).
+//   - limit: Number of body bytes to buffer.
+//   - onError: Reports mirror failures and dropped copies.
+func ShadowMirrorNew(
+	targets []*url.URL,
+	sampleRate float64,
+	timeout time.Duration,
+	concurrency int,
+	limit int64,
+	onError func(target *url.URL, e error),
+) *ShadowMirror {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ShadowMirror{
+		Client:     http.DefaultClient,
+		targets:    targets,
+		sampleRate: sampleRate,
+		timeout:    timeout,
+		limit:      limit,
+		onError:    onError,
+		sem:        make(chan struct{}, concurrency),
+	}
+}
+
+func (m *ShadowMirror) send(target *url.URL, method, uri string, header http.Header, body []byte) error {
+	u, e := targetURL(target, uri)
+	if nil != e {
+		return e
+	}
+	var ctx context.Context = context.Background()
+	if 0 < m.timeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	q, e := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if nil != e {
+		return e
+	}
+	q.Header = header.Clone()
+	q.Header.Del("Content-Length")
+	res, e := m.Client.Do(q)
+	if nil != e {
+		return e
+	}
+	_, _ = io.Copy(io.Discard, res.Body) // reuse the connection
+	return res.Body.Close()
+}
+
+// mirror starts mirrored requests without blocking.
+func (m *ShadowMirror) mirror(q *http.Request, body []byte) {
+	if m.sampleRate < 1.0 && m.sampleRate <= rand.Float64() {
+		return
+	}
+	var method string = q.Method
+	var uri string = q.URL.RequestURI()
+	var header http.Header = q.Header.Clone()
+	for _, target := range m.targets {
+		select {
+		case m.sem <- struct{}{}:
+		default:
+			m.onError(target, ErrQueueFull)
+			continue
+		}
+		m.running.Add(1)
+		go func(target *url.URL) {
+			defer m.running.Done()
+			defer func() { <-m.sem }()
+			var e error = m.send(target, method, uri, header, body)
+			if nil != e {
+				m.onError(target, e)
+			}
+		}(target)
+	}
+}
+
+// Wait waits until running mirrored requests finish.
+func (m *ShadowMirror) Wait() { m.running.Wait() }
+
+// RequestSaverMirroredNew creates a wrapper which mirrors successfully saved requests.
+func RequestSaverMirroredNew[R any](m *ShadowMirror) func(RequestSaverStd[R]) RequestSaverStd[R] {
+	return func(original RequestSaverStd[R]) RequestSaverStd[R] {
+		return func(q *http.Request) (result R, e error) {
+			body, e := bodyCapture(q, m.limit)
+			if nil != e {
+				return
+			}
+			result, e = original(requestWithBody(q, body))
+			if nil == e {
+				m.mirror(q, body)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package saver_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestMirror(t *testing.T) {
+	t.Parallel()
+
+	var shadowNew func(t *testing.T, received *sync.Map, delay time.Duration) *url.URL = func(
+		t *testing.T,
+		received *sync.Map,
+		delay time.Duration,
+	) *url.URL {
+		var svr *httptest.Server = httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, q *http.Request) {
+				time.Sleep(delay)
+				body, _ := io.ReadAll(q.Body)
+				received.Store(q.URL.RequestURI(), string(body))
+				w.WriteHeader(500)
+			},
+		))
+		t.Cleanup(svr.Close)
+		u, e := url.Parse(svr.URL)
+		if nil != e {
+			t.Fatal(e)
+		}
+		return u
+	}
+
+	var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+		return int64(len(serialized)), nil
+	}
+
+	t.Run("mirrored", func(t *testing.T) {
+		t.Parallel()
+
+		var received1, received2 sync.Map
+		var m *saver.ShadowMirror = saver.ShadowMirrorNew(
+			[]*url.URL{shadowNew(t, &received1, 0), shadowNew(t, &received2, 0)},
+			1.0,
+			time.Second,
+			4,
+			1024,
+			func(_ *url.URL, e error) { t.Errorf("unexpected error: %v", e) },
+		)
+		var rs saver.RequestSaverStd[int64] = saver.RequestSaverMirroredNew[int64](m)(
+			sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew()),
+		)
+
+		cnt, e := rs(httptest.NewRequest("POST", "/hooks?id=1", strings.NewReader("hw")))
+		t.Run("no error", assertNil(e))
+		t.Run("saved", assertEq(cnt, 2))
+
+		m.Wait()
+		body1, _ := received1.Load("/hooks?id=1")
+		body2, _ := received2.Load("/hooks?id=1")
+		t.Run("shadow 1", assertEq(body1, any("hw")))
+		t.Run("shadow 2", assertEq(body2, any("hw")))
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		t.Parallel()
+
+		var received sync.Map
+		var m *saver.ShadowMirror = saver.ShadowMirrorNew(
+			[]*url.URL{shadowNew(t, &received, 0)},
+			0.0,
+			time.Second,
+			4,
+			1024,
+			func(_ *url.URL, e error) { t.Errorf("unexpected error: %v", e) },
+		)
+		var rs saver.RequestSaverStd[int64] = saver.RequestSaverMirroredNew[int64](m)(
+			sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew()),
+		)
+		_, e := rs(httptest.NewRequest("POST", "/", strings.NewReader("hw")))
+		t.Run("no error", assertNil(e))
+		m.Wait()
+		_, found := received.Load("/")
+		t.Run("not mirrored", assertEq(found, false))
+	})
+
+	t.Run("timeout and cap", func(t *testing.T) {
+		t.Parallel()
+
+		var received sync.Map
+		var lock sync.Mutex
+		var failures []error
+		var m *saver.ShadowMirror = saver.ShadowMirrorNew(
+			[]*url.URL{shadowNew(t, &received, 200*time.Millisecond)},
+			1.0,
+			10*time.Millisecond,
+			1,
+			1024,
+			func(_ *url.URL, e error) {
+				lock.Lock()
+				defer lock.Unlock()
+				failures = append(failures, e)
+			},
+		)
+		var rs saver.RequestSaverStd[int64] = saver.RequestSaverMirroredNew[int64](m)(
+			sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew()),
+		)
+
+		_, e1 := rs(httptest.NewRequest("POST", "/", strings.NewReader("1")))
+		_, e2 := rs(httptest.NewRequest("POST", "/", strings.NewReader("2")))
+		t.Run("primary 1", assertNil(e1))
+		t.Run("primary 2", assertNil(e2))
+
+		m.Wait()
+		lock.Lock()
+		defer lock.Unlock()
+		t.Run("2 failures", assertEq(len(failures), 2))
+		var dropped bool
+		for _, failure := range failures {
+			dropped = dropped || errors.Is(failure, saver.ErrQueueFull)
+		}
+		t.Run("dropped", assertTrue(dropped))
+	})
+
+	t.Run("zero values", func(t *testing.T) {
+		t.Parallel()
+
+		var received sync.Map
+		var m *saver.ShadowMirror = saver.ShadowMirrorNew(
+			[]*url.URL{shadowNew(t, &received, 0)},
+			1.0,
+			0,
+			0,
+			1024,
+			func(_ *url.URL, e error) { t.Errorf("unexpected error: %v", e) },
+		)
+		var rs saver.RequestSaverStd[int64] = saver.RequestSaverMirroredNew[int64](m)(
+			sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew()),
+		)
+		_, e := rs(httptest.NewRequest("POST", "/zero", strings.NewReader("hw")))
+		t.Run("no error", assertNil(e))
+
+		m.Wait()
+		body, _ := received.Load("/zero")
+		t.Run("mirrored without a timeout", assertEq(body, any("hw")))
+	})
+}
@@ -0,0 +1,310 @@
+package saver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NameGen creates a name of a record(sample: the nameGen of RequestSaverNewFsSelfChecked).
+//
+// Names of NameGenUUIDv7New, NameGenULIDNew and NameGenSequenceNew can also be used as a RecordIDGen.
+type NameGen func() (name string)
+
+// monotonic gets a unix millisecond and a counter which increase for each call.
+type monotonic struct {
+	lock    sync.Mutex
+	now     func() time.Time
+	lastMs  int64
+	counter uint64
+}
+
+// next gets a timestamp and a counter; the counter starts at a random value(top bit zero) for each millisecond.
+//
+// The timestamp is advanced when the counter overflows.
+func (m *monotonic) next(counterBits uint, random func() uint64) (ms int64, counter uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ms = m.now().UnixMilli()
+	var limit uint64 = 1 << counterBits
+	switch {
+	case m.lastMs < ms:
+		m.lastMs = ms
+		m.counter = random() & (limit>>1 - 1)
+	default:
+		m.counter++
+		if limit <= m.counter {
+			m.lastMs++
+			m.counter = 0
+		}
+	}
+	return m.lastMs, m.counter
+}
+
+func randomBytes(size int) []byte {
+	var b []byte = make([]byte, size)
+	_, _ = rand.Read(b) // always nil error(crypto/rand)
+	return b
+}
+
+func randomUint64() uint64 { return binary.BigEndian.Uint64(randomBytes(8)) }
+
+// NameGenUUIDv7New creates a generator of UUIDv7(RFC 9562) names ordered by the creation time.
+//
+// The 12 bit rand_a field is a counter; names created in a process never collide and are sorted.
+//
+// # Arguments
+//   - now: Gets the current time.
+func NameGenUUIDv7New(now func() time.Time) NameGen {
+	var m *monotonic = &monotonic{now: now}
+	return func() (name string) {
+		ms, counter := m.next(12, randomUint64)
+		var u []byte = randomBytes(16)
+		binary.BigEndian.PutUint16(u[0:2], uint16(ms>>32))
+		binary.BigEndian.PutUint32(u[2:6], uint32(ms))
+		binary.BigEndian.PutUint16(u[6:8], 0x7000|uint16(counter))
+		u[8] = 0x80 | (u[8] & 0x3f)
+		var h string = hex.EncodeToString(u)
+		return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+	}
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NameGenULIDNew creates a generator of ULID names ordered by the creation time.
+//
+// The random part is incremented within a millisecond(monotonic ULIDs).
+//
+// # Arguments
+//   - now: Gets the current time.
+func NameGenULIDNew(now func() time.Time) NameGen {
+	var lock sync.Mutex
+	var lastMs int64 = -1
+	var random []byte // 80 bits
+	return func() (name string) {
+		lock.Lock()
+		var ms int64 = now().UnixMilli()
+		switch {
+		case lastMs < ms:
+			lastMs = ms
+			random = randomBytes(10)
+			random[0] &= 0x7f // room for increments
+		default:
+			var i int = len(random) - 1
+			for ; 0 <= i; i-- {
+				random[i]++
+				if 0 != random[i] {
+					break
+				}
+			}
+			if i < 0 {
+				lastMs++ // overflow
+			}
+		}
+		var u [16]byte
+		binary.BigEndian.PutUint16(u[0:2], uint16(lastMs>>32))
+		binary.BigEndian.PutUint32(u[2:6], uint32(lastMs))
+		copy(u[6:], random)
+		lock.Unlock()
+
+		// 26 characters of 5 bits: 130 bits with 2 leading zero bits
+		var b [26]byte
+		for i := range b {
+			var value byte = 0
+			for bit := 5*i - 2; bit < 5*i+3; bit++ {
+				value <<= 1
+				if 0 <= bit && 0 != u[bit/8]&(0x80>>(bit%8)) {
+					value |= 1
+				}
+			}
+			b[i] = crockford[value]
+		}
+		return string(b[:])
+	}
+}
+
+// ErrSequenceUnavailable is returned when the state of a sequence can not be written.
+var ErrSequenceUnavailable error = errors.New("sequence state can not be written")
+
+// Sequence gets the next number of a persistent sequence.
+type Sequence func() (n uint64, e error)
+
+// sequence keeps a block of numbers reserved in a state file.
+type sequence struct {
+	lock  sync.Mutex
+	state string
+	block uint64
+	next  uint64
+	end   uint64
+}
+
+func (s *sequence) reserve(end uint64) error {
+	var tmp string = s.state + ".tmp"
+	f, e := os.Create(tmp)
+	if nil != e {
+		return e
+	}
+	_, e = f.WriteString(strconv.FormatUint(end, 10) + "\n")
+	if nil == e {
+		e = f.Sync()
+	}
+	e = errors.Join(e, f.Close())
+	if nil != e {
+		return e
+	}
+	return os.Rename(tmp, s.state)
+}
+
+// get gets the next number; the number is not used if the state can not be written(after retries).
+func (s *sequence) get() (n uint64, e error) {
+	var wait time.Duration = 10 * time.Millisecond
+	for retry := 0; s.end <= s.next; retry++ {
+		e = s.reserve(s.next + s.block)
+		if nil == e {
+			s.end = s.next + s.block
+			break
+		}
+		if 3 <= retry {
+			return s.next, fmt.Errorf("%w: %s: %w", ErrSequenceUnavailable, s.state, e)
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+	n = s.next
+	s.next++
+	return n, nil
+}
+
+func sequenceNew(state string, block uint64) (*sequence, error) {
+	if 0 == block {
+		block = 1
+	}
+	var s *sequence = &sequence{state: state, block: block, next: 1}
+	content, e := os.ReadFile(state)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+	case nil != e:
+		return nil, e
+	default:
+		s.next, e = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+		if nil != e {
+			return nil, fmt.Errorf("invalid sequence state %s: %w", state, e)
+		}
+	}
+	s.end = s.next + block
+	e = s.reserve(s.end)
+	if nil != e {
+		return nil, e
+	}
+	return s, nil
+}
+
+// SequenceNew creates a sequence starting at 1 whose numbers persist across restarts.
+//
+// The state file keeps the end of a reserved block; numbers of an unused block are skipped after a restart.
+// Numbers are never used beyond the saved block: a state which can not be written is retried 3 times
+// (about 70 milliseconds) and then ErrSequenceUnavailable is returned.
+//
+// # Arguments
+//   - state: A file which keeps the sequence.
+//   - block: Number of numbers reserved by a write of the state file.
+func SequenceNew(state string, block uint64) (Sequence, error) {
+	s, e := sequenceNew(state, block)
+	if nil != e {
+		return nil, e
+	}
+	return func() (n uint64, e error) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.get()
+	}, nil
+}
+
+// NameGenSequenceNew creates a generator of zero padded(20 digits) sequence numbers which persist across restarts.
+//
+// Numbers are reserved as SequenceNew.
+// Saves are not blocked while the state can not be written(ErrSequenceUnavailable):
+// the next number with a random suffix is used instead(sample: 00000000000000000042-9f86d081884c7d65),
+// which never collides with other names; the error is passed to onError.
+//
+// # Arguments
+//   - state: A file which keeps the sequence.
+//   - block: Number of names reserved by a write of the state file.
+//   - onError: Reports a state which can not be written(nil: ignored).
+func NameGenSequenceNew(state string, block uint64, onError func(error)) (NameGen, error) {
+	s, e := sequenceNew(state, block)
+	if nil != e {
+		return nil, e
+	}
+	return func() (name string) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		n, e := s.get()
+		if nil != e {
+			if nil != onError {
+				onError(e)
+			}
+			return fmt.Sprintf("%020d-%s", n, hex.EncodeToString(randomBytes(8)))
+		}
+		return fmt.Sprintf("%020d", n)
+	}, nil
+}
+
+// NameGenTimePartitionedNew creates a generator which puts names in time-partitioned directories.
+//
+// # Arguments
+//   - layout: A Go time layout of directories(sample: "dt=2006-01-02/hr=15").
+//   - now: Gets the current time(formatted in UTC).
+//   - leaf: Creates a name in a directory.
+func NameGenTimePartitionedNew(layout string, now func() time.Time, leaf NameGen) NameGen {
+	return func() (name string) {
+		return filepath.Join(filepath.FromSlash(now().UTC().Format(layout)), leaf())
+	}
+}
+
+// NameGenHashShardedNew creates a generator which puts names in directories named after the hash of a name.
+//
+// A name "x" is put in "2d/71/x" if levels is 2 and width is 2(sha256("x") starts with 2d71).
+//
+// # Arguments
+//   - levels: Number of directory levels.
+//   - width: Number of hex digits of a directory name(levels * width must not exceed 64).
+//   - leaf: Creates a name.
+func NameGenHashShardedNew(levels, width int, leaf NameGen) NameGen {
+	return func() (name string) {
+		name = leaf()
+		var digest [sha256.Size]byte = sha256.Sum256([]byte(filepath.Base(name)))
+		var h string = hex.EncodeToString(digest[:])
+		var parts []string = make([]string, 0, levels+1)
+		for i := 0; i < levels && (i+1)*width <= len(h); i++ {
+			parts = append(parts, h[i*width:(i+1)*width])
+		}
+		return filepath.Join(append(parts, name)...)
+	}
+}
+
+// NameGenInDirNew creates a generator of full paths which creates missing directories on demand.
+//
+// A directory which can not be created is reported by the writer of the file.
+//
+// # Arguments
+//   - root: The root directory.
+//   - suffix: A suffix of names(sample: ".tar").
+//   - leaf: Creates a relative path(sample: NameGenTimePartitionedNew).
+func NameGenInDirNew(root, suffix string, leaf NameGen) func() (fullpath string) {
+	return func() (fullpath string) {
+		fullpath = filepath.Join(root, leaf()+suffix)
+		_ = os.MkdirAll(filepath.Dir(fullpath), 0755) // the error is returned when the file is created
+		return fullpath
+	}
+}
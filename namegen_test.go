@@ -0,0 +1,144 @@
+package saver_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestNameGen(t *testing.T) {
+	t.Parallel()
+
+	var fixed func(t time.Time) func() time.Time = func(t time.Time) func() time.Time {
+		return func() time.Time { return t }
+	}
+
+	var sortedUnique func(gen saver.NameGen, n int) bool = func(gen saver.NameGen, n int) bool {
+		var names []string
+		for i := 0; i < n; i++ {
+			names = append(names, gen())
+		}
+		var ok bool = sort.StringsAreSorted(names)
+		for i := 1; i < len(names); i++ {
+			ok = ok && names[i-1] != names[i]
+		}
+		return ok
+	}
+
+	t.Run("UUIDv7", func(t *testing.T) {
+		t.Parallel()
+
+		// 2022-02-22T19:22:22Z(017f22e2-79b0-7...: RFC 9562 A.6)
+		var gen saver.NameGen = saver.NameGenUUIDv7New(fixed(time.UnixMilli(0x017f22e279b0)))
+		var name string = gen()
+		var pattern *regexp.Regexp = regexp.MustCompile(`^017f22e2-79b0-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		t.Run("format", assertTrue(pattern.MatchString(name)))
+		t.Run("monotonic within a millisecond", assertTrue(sortedUnique(gen, 5000)))
+
+		var id saver.RecordIDGen = saver.RecordIDGen(saver.NameGenUUIDv7New(time.Now))
+		t.Run("record id", assertEq(len(id()), 36))
+	})
+
+	t.Run("ULID", func(t *testing.T) {
+		t.Parallel()
+
+		var gen saver.NameGen = saver.NameGenULIDNew(fixed(time.UnixMilli(1469918176385)))
+		var name string = gen()
+		t.Run("length", assertEq(len(name), 26))
+		t.Run("time", assertEq(name[:10], "01ARYZ6S41"))
+		t.Run("monotonic within a millisecond", assertTrue(sortedUnique(gen, 5000)))
+	})
+
+	t.Run("sequence", func(t *testing.T) {
+		t.Parallel()
+
+		var state string = filepath.Join(t.TempDir(), "seq")
+		gen, e := saver.NameGenSequenceNew(state, 10, nil)
+		t.Run("no error", assertNil(e))
+		t.Run("first", assertEq(gen(), "00000000000000000001"))
+		for i := 0; i < 11; i++ {
+			_ = gen()
+		}
+		t.Run("next block", assertEq(gen(), "00000000000000000013"))
+
+		restarted, e := saver.NameGenSequenceNew(state, 10, nil)
+		t.Run("no restart error", assertNil(e))
+		t.Run("after the reserved block", assertEq(restarted(), "00000000000000000021"))
+
+		// the state can not be written while a directory occupies the temporary file
+		var blocked string = filepath.Join(t.TempDir(), "blocked")
+		var errs []error
+		single, _ := saver.NameGenSequenceNew(blocked, 1, func(e error) { errs = append(errs, e) })
+		t.Run("reserved", assertEq(single(), "00000000000000000001"))
+		_ = os.Mkdir(blocked+".tmp", 0755)
+		var fallback string = single()
+		t.Run("not blocked", assertTrue(strings.HasPrefix(fallback, "00000000000000000002-")))
+		t.Run("unavailable", assertTrue(1 == len(errs) && errors.Is(errs[0], saver.ErrSequenceUnavailable)))
+		_ = os.Remove(blocked + ".tmp")
+		t.Run("after the state is written", assertEq(single(), "00000000000000000002"))
+		saved, _ := os.ReadFile(blocked)
+		t.Run("saved reservation", assertEq(string(saved), "3\n"))
+
+		_ = os.WriteFile(state, []byte("x"), 0644)
+		_, e = saver.NameGenSequenceNew(state, 10, nil)
+		t.Run("invalid state", assertTrue(nil != e))
+	})
+
+	t.Run("SequenceNew", func(t *testing.T) {
+		t.Parallel()
+
+		var state string = filepath.Join(t.TempDir(), "seq")
+		seq, e := saver.SequenceNew(state, 1)
+		t.Run("no error", assertNil(e))
+		n, e := seq()
+		t.Run("no next error", assertNil(e))
+		t.Run("first", assertEq(n, 1))
+
+		_ = os.Mkdir(state+".tmp", 0755)
+		_, e = seq()
+		t.Run("unavailable", assertTrue(errors.Is(e, saver.ErrSequenceUnavailable)))
+		_ = os.Remove(state + ".tmp")
+		n, _ = seq()
+		t.Run("number not used", assertEq(n, 2))
+	})
+
+	t.Run("directories", func(t *testing.T) {
+		t.Parallel()
+
+		var root string = t.TempDir()
+		var names []string = []string{"x", "y"}
+		var lock sync.Mutex
+		var leaf saver.NameGen = func() string {
+			lock.Lock()
+			defer lock.Unlock()
+			var name string = names[0]
+			names = names[1:]
+			return name
+		}
+		var gen func() string = saver.NameGenInDirNew(root, ".tar", saver.NameGenTimePartitionedNew(
+			"dt=2006-01-02/hr=15",
+			fixed(time.Date(2026, 10, 17, 13, 4, 5, 0, time.UTC)),
+			saver.NameGenHashShardedNew(2, 2, leaf),
+		))
+		var fullpath string = gen()
+		t.Run("path", assertEq(fullpath, filepath.Join(root, "dt=2026-10-17", "hr=13", "2d", "71", "x.tar")))
+
+		var sav saver.RequestSaver[[]byte, int64] = saver.RequestSaverNewFsSelfCheckedWithFileMode(
+			func(q []byte) ([]byte, error) { return q, nil },
+			gen,
+			0644,
+		)
+		_, e := sav([]byte("hw"))
+		t.Run("directories created", assertNil(e))
+		matches, _ := filepath.Glob(filepath.Join(root, "dt=2026-10-17", "hr=13", "*", "*", "y.tar"))
+		t.Run("saved", assertEq(len(matches), 1))
+	})
+}
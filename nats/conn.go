@@ -0,0 +1,224 @@
+// Package nats provides a small NATS core-protocol client and a BytesSaver which publishes requests.
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProtocol is returned when a server sends an unexpected message.
+var ErrProtocol error = errors.New("invalid NATS message")
+
+// ErrMaxPayload is returned when a payload is larger than the limit of a server.
+var ErrMaxPayload error = errors.New("payload too large")
+
+// ErrInvalidSubject is returned when a subject is empty or contains white spaces.
+var ErrInvalidSubject error = errors.New("invalid subject")
+
+// Error is an error message(-ERR) sent by a server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Info is the INFO message sent by a server.
+type Info struct {
+	ServerID   string `json:"server_id"`
+	MaxPayload int64  `json:"max_payload"`
+}
+
+// Conn is a connection to a NATS server which can publish messages.
+//
+// A reader goroutine answers PING messages of the server.
+type Conn struct {
+	Info Info
+
+	conn    net.Conn
+	timeout time.Duration
+
+	lock sync.Mutex // guards wtr
+	wtr  *bufio.Writer
+
+	flushLock sync.Mutex
+	pongs     chan struct{}
+
+	done    chan struct{}
+	errLock sync.Mutex
+	err     error
+}
+
+func validSubject(subject string) error {
+	if "" == subject || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("%w: %q", ErrInvalidSubject, subject)
+	}
+	return nil
+}
+
+// ConnNew creates a connection from a net.Conn.
+//
+// The INFO message is read and a CONNECT message is sent.
+//
+// # Arguments
+//   - conn: A connected stream(tcp or unix).
+//   - timeout: Deadline of a handshake, a write or a flush(0: no deadline).
+func ConnNew(conn net.Conn, timeout time.Duration) (*Conn, error) {
+	var rdr *bufio.Reader = bufio.NewReader(conn)
+	if 0 < timeout {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout)) // errors are returned by ReadString
+	}
+	line, e := rdr.ReadString('\n')
+	if nil != e {
+		return nil, errors.Join(e, conn.Close())
+	}
+	op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+	if "INFO" != strings.ToUpper(op) {
+		return nil, errors.Join(fmt.Errorf("%w: %q", ErrProtocol, line), conn.Close())
+	}
+	var c *Conn = &Conn{
+		conn:    conn,
+		timeout: timeout,
+		wtr:     bufio.NewWriter(conn),
+		pongs:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	e = json.Unmarshal([]byte(args), &c.Info)
+	if nil != e {
+		return nil, errors.Join(e, conn.Close())
+	}
+	_ = conn.SetReadDeadline(time.Time{}) // errors are returned by ReadString
+	e = c.write(func(w *bufio.Writer) {
+		_, _ = w.WriteString(`CONNECT {"verbose":false,"pedantic":false,"name":"go-simple-req-saver"}` + "\r\n")
+	})
+	if nil != e {
+		return nil, errors.Join(e, conn.Close())
+	}
+	go c.read(rdr)
+	return c, nil
+}
+
+// Dial connects to a server.
+func Dial(network, address string, timeout time.Duration) (*Conn, error) {
+	conn, e := net.DialTimeout(network, address, timeout)
+	if nil != e {
+		return nil, e
+	}
+	return ConnNew(conn, timeout)
+}
+
+func (c *Conn) fail(e error) {
+	c.errLock.Lock()
+	defer c.errLock.Unlock()
+	if nil != c.err {
+		return
+	}
+	c.err = e
+	_ = c.conn.Close() // the first error is kept
+	close(c.done)
+}
+
+// Err gets the error which broke the connection(nil: not broken).
+func (c *Conn) Err() error {
+	c.errLock.Lock()
+	defer c.errLock.Unlock()
+	return c.err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.fail(net.ErrClosed)
+	return nil
+}
+
+func (c *Conn) read(rdr *bufio.Reader) {
+	for {
+		line, e := rdr.ReadString('\n')
+		if nil != e {
+			c.fail(e)
+			return
+		}
+		op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch strings.ToUpper(op) {
+		case "PING":
+			_ = c.write(func(w *bufio.Writer) { _, _ = w.WriteString("PONG\r\n") }) // a broken conn is kept in err
+		case "PONG":
+			select {
+			case c.pongs <- struct{}{}:
+			default:
+			}
+		case "+OK", "INFO":
+		case "-ERR":
+			c.fail(Error(strings.Trim(args, "' ")))
+			return
+		default:
+			c.fail(fmt.Errorf("%w: %q", ErrProtocol, line))
+			return
+		}
+	}
+}
+
+func (c *Conn) write(f func(w *bufio.Writer)) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if 0 < c.timeout {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout)) // errors are returned by Flush
+	}
+	f(c.wtr) // errors are returned by Flush
+	var e error = c.wtr.Flush()
+	if nil != e {
+		c.fail(e)
+	}
+	return e
+}
+
+// Publish sends a message.
+func (c *Conn) Publish(subject string, payload []byte) error {
+	var e error = validSubject(subject)
+	if nil != e {
+		return e
+	}
+	if 0 < c.Info.MaxPayload && c.Info.MaxPayload < int64(len(payload)) {
+		return fmt.Errorf("%w: %d > %d", ErrMaxPayload, len(payload), c.Info.MaxPayload)
+	}
+	e = c.Err()
+	if nil != e {
+		return e
+	}
+	return c.write(func(w *bufio.Writer) {
+		_, _ = fmt.Fprintf(w, "PUB %s %d\r\n", subject, len(payload))
+		_, _ = w.Write(payload)
+		_, _ = w.WriteString("\r\n")
+	})
+}
+
+// Flush sends a PING and waits for the PONG; messages sent before are processed by the server.
+func (c *Conn) Flush() error {
+	c.flushLock.Lock()
+	defer c.flushLock.Unlock()
+	select {
+	case <-c.pongs: // a stale PONG
+	default:
+	}
+	var e error = c.write(func(w *bufio.Writer) { _, _ = w.WriteString("PING\r\n") })
+	if nil != e {
+		return e
+	}
+	var timeout <-chan time.Time
+	if 0 < c.timeout {
+		timeout = time.After(c.timeout)
+	}
+	select {
+	case <-c.pongs:
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-timeout:
+		e = fmt.Errorf("no PONG within %v", c.timeout)
+		c.fail(e)
+		return e
+	}
+}
@@ -0,0 +1,142 @@
+package nats_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+	"github.com/takanoriyanagitani/go-simple-req-saver/nats"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+func testPublisherNew(t *testing.T, s *testServer, bufferLimit int) *nats.Publisher {
+	var p *nats.Publisher = nats.PublisherNew(func() (*nats.Conn, error) {
+		return nats.Dial("tcp", s.addr, time.Second)
+	}, bufferLimit)
+	p.ReconnectWait = 0
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestConn(t *testing.T) {
+	t.Parallel()
+
+	var s *testServer = testServerNew(t)
+	c, e := nats.Dial("tcp", s.addr, time.Second)
+	t.Run("no dial error", assertNil(e))
+	defer c.Close()
+	t.Run("info", assertEq(c.Info.MaxPayload, 64))
+
+	t.Run("no publish error", assertNil(c.Publish("a.b", []byte("hw"))))
+	t.Run("no flush error", assertNil(c.Flush()))
+	t.Run("too large", assertTrue(errors.Is(c.Publish("a.b", make([]byte, 65)), nats.ErrMaxPayload)))
+	t.Run("invalid subject", assertTrue(errors.Is(c.Publish("a b", nil), nats.ErrInvalidSubject)))
+
+	// the PONG to the PING of the server is sent by the reader goroutine
+	var pongs int = 0
+	for deadline := time.Now().Add(time.Second); 0 == pongs && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		s.lock.Lock()
+		pongs = s.pongs
+		s.lock.Unlock()
+	}
+	t.Run("ping answered", assertEq(pongs, 1))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t.Run("connected", assertEq(s.connects, 1))
+	t.Run("published", assertEq(len(s.messages), 1))
+	t.Run("subject", assertEq(s.messages[0].subject, "a.b"))
+	t.Run("payload", assertEq(s.messages[0].payload, "hw"))
+}
+
+func TestPublisher(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SubjectTemplateNew", func(t *testing.T) {
+		t.Parallel()
+
+		subjectOf, e := nats.SubjectTemplateNew("cap.{method}.{host}.{path|}", time.Now)
+		t.Run("no template error", assertNil(e))
+
+		var q = httptest.NewRequest("POST", "/v1/items?x=1", nil)
+		q.Host = "api.example.com"
+		serialized, _ := saver.ExchangeStd2bytesTar(saver.ExchangeStdNew(q, nil, "id-1", time.Now()))
+		subject, e := subjectOf(serialized)
+		t.Run("no subject error", assertNil(e))
+		t.Run("subject", assertEq(subject, "cap.POST.api_example_com.v1.items"))
+
+		_, e = subjectOf([]byte("not an archive"))
+		t.Run("missing values", assertTrue(errors.Is(e, saver.ErrKeyValueMissing)))
+		withDefaults, _ := nats.SubjectTemplateNew("cap.{method|}.{host|}.{path|}", time.Now)
+		subject, _ = withDefaults([]byte("not an archive"))
+		t.Run("defaults", assertEq(subject, "cap._._._"))
+
+		_, e = nats.SubjectTemplateNew("cap.{nosuch}", time.Now)
+		t.Run("unknown placeholder", assertTrue(nil != e))
+		_, e = nats.SubjectTemplateNew("cap {id}", time.Now)
+		t.Run("white space", assertTrue(errors.Is(e, nats.ErrInvalidSubject)))
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		t.Parallel()
+
+		var s *testServer = testServerNew(t)
+		var p *nats.Publisher = testPublisherNew(t, s, 16)
+		subjectOf, _ := nats.SubjectTemplateNew("cap", time.Now)
+		var sav saver.BytesSaver = nats.SaverNew(p, subjectOf)
+
+		_, e := sav([]byte("first"))
+		t.Run("no first error", assertNil(e))
+		t.Run("no first flush error", assertNil(p.Flush()))
+
+		s.setDown(true)
+		t.Run("broken", assertTrue(nil != p.Flush()))
+
+		_, e = sav([]byte("second"))
+		t.Run("buffered", assertNil(e))
+		_, e = sav([]byte("third"))
+		t.Run("buffered again", assertNil(e))
+		t.Run("buffered count", assertEq(p.Buffered(), 2))
+		_, e = sav([]byte("too many bytes"))
+		t.Run("buffer full", assertTrue(errors.Is(e, nats.ErrBufferFull)))
+		t.Run("not reconnected", assertTrue(nil != p.Flush()))
+
+		s.setDown(false)
+		t.Run("no flush error", assertNil(p.Flush()))
+		t.Run("drained", assertEq(p.Buffered(), 0))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		var payloads []string
+		for _, m := range s.messages {
+			payloads = append(payloads, m.payload)
+		}
+		t.Run("in order", assertEq(strings.Join(payloads, ","), "first,second,third"))
+		t.Run("reconnected", assertEq(s.connects, 2))
+	})
+}
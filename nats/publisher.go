@@ -0,0 +1,212 @@
+package nats
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// ErrBufferFull is returned when a message can not be buffered while disconnected.
+var ErrBufferFull error = errors.New("reconnect buffer full")
+
+type message struct {
+	subject string
+	payload []byte
+}
+
+// Publisher publishes messages and reconnects to a server on demand.
+//
+// Messages are buffered while the server is unreachable and are sent after a reconnect.
+type Publisher struct {
+	// ReconnectWait is the minimum interval between connection attempts.
+	ReconnectWait time.Duration
+
+	lock        sync.Mutex
+	dial        func() (*Conn, error)
+	conn        *Conn
+	lastDial    time.Time
+	bufferLimit int
+	pending     []message
+	pendingSize int
+	now         func() time.Time
+}
+
+// PublisherNew creates a publisher which waits a second between connection attempts.
+//
+// # Arguments
+//   - dial: Connects to a server(sample: Dial).
+//   - bufferLimit: The total size of payloads buffered while disconnected.
+func PublisherNew(dial func() (*Conn, error), bufferLimit int) *Publisher {
+	return &Publisher{
+		ReconnectWait: time.Second,
+		dial:          dial,
+		bufferLimit:   bufferLimit,
+		now:           time.Now,
+	}
+}
+
+// connected gets a live connection(nil: disconnected).
+func (p *Publisher) connected() (*Conn, error) {
+	if nil != p.conn && nil == p.conn.Err() {
+		return p.conn, nil
+	}
+	p.conn = nil
+	var now time.Time = p.now()
+	if now.Sub(p.lastDial) < p.ReconnectWait {
+		return nil, nil
+	}
+	p.lastDial = now
+	c, e := p.dial()
+	if nil != e {
+		return nil, e
+	}
+	p.conn = c
+	return c, nil
+}
+
+// drain sends buffered messages in order.
+func (p *Publisher) drain(c *Conn) error {
+	for 0 < len(p.pending) {
+		var m message = p.pending[0]
+		var e error = c.Publish(m.subject, m.payload)
+		if errors.Is(e, ErrMaxPayload) {
+			e = nil // can never be sent
+		}
+		if nil != e {
+			return e
+		}
+		p.pending = p.pending[1:]
+		p.pendingSize -= len(m.payload)
+	}
+	p.pending = nil
+	return nil
+}
+
+func (p *Publisher) buffer(subject string, payload []byte, cause error) error {
+	if p.bufferLimit < p.pendingSize+len(payload) {
+		return errors.Join(ErrBufferFull, cause)
+	}
+	p.pending = append(p.pending, message{subject: subject, payload: bytes.Clone(payload)})
+	p.pendingSize += len(payload)
+	return nil
+}
+
+// Publish sends a message or buffers it while disconnected.
+func (p *Publisher) Publish(subject string, payload []byte) error {
+	var e error = validSubject(subject)
+	if nil != e {
+		return e
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	c, e := p.connected()
+	if nil != c {
+		e = p.drain(c)
+		if nil == e {
+			e = c.Publish(subject, payload)
+			if nil == e || errors.Is(e, ErrMaxPayload) {
+				return e
+			}
+		}
+	}
+	return p.buffer(subject, payload, e)
+}
+
+// Flush reconnects if disconnected, sends buffered messages and waits for the server.
+func (p *Publisher) Flush() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lastDial = time.Time{}
+	c, e := p.connected()
+	if nil != e {
+		return e
+	}
+	e = p.drain(c)
+	if nil != e {
+		return e
+	}
+	return c.Flush()
+}
+
+// Buffered gets the number of messages waiting for a reconnect.
+func (p *Publisher) Buffered() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.pending)
+}
+
+// Close closes the connection; buffered messages are dropped.
+func (p *Publisher) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pending = nil
+	p.pendingSize = 0
+	if nil == p.conn {
+		return nil
+	}
+	var e error = p.conn.Close()
+	p.conn = nil
+	return e
+}
+
+// SubjectFunc gets a subject of a serialized request.
+type SubjectFunc func(serialized []byte) (subject string, e error)
+
+// subjectToken replaces characters which can not be used in a subject token.
+var subjectToken *strings.Replacer = strings.NewReplacer(
+	".", "_", "*", "_", ">", "_", " ", "_", "\t", "_", "\r", "_", "\n", "_",
+)
+
+// SubjectToken escapes a value as subject tokens.
+//
+// Segments separated by "/" become tokens(sample: /v1/items -> v1.items); an empty value becomes "_".
+func SubjectToken(value string) string {
+	var tokens []string
+	for _, segment := range strings.Split(value, "/") {
+		if "" != segment {
+			tokens = append(tokens, subjectToken.Replace(segment))
+		}
+	}
+	if 0 == len(tokens) {
+		return "_"
+	}
+	return strings.Join(tokens, ".")
+}
+
+// SubjectTemplateNew creates a subject func from a key template(see saver.KeyTemplateNew).
+//
+// Values of placeholders are escaped by SubjectToken(sample: "captures.{method}.{path|}" -> captures.POST.v1.items).
+//
+// # Arguments
+//   - template: A subject template.
+//   - now: Gets the current time if the received time is unknown.
+func SubjectTemplateNew(template string, now func() time.Time) (SubjectFunc, error) {
+	var e error = validSubject(strings.ReplaceAll(template, "{", ""))
+	if nil != e {
+		return nil, e
+	}
+	tmpl, e := saver.KeyTemplateEscapedNew(template, now, SubjectToken)
+	if nil != e {
+		return nil, e
+	}
+	return tmpl.ForSerialized(), nil
+}
+
+// SaverNew creates a saver which publishes each serialized request.
+//
+// # Arguments
+//   - p: Publishes messages.
+//   - subjectOf: Gets a subject(sample: SubjectTemplateNew).
+func SaverNew(p *Publisher, subjectOf SubjectFunc) saver.BytesSaver {
+	return func(serialized []byte) (bytesCount int64, e error) {
+		subject, e := subjectOf(serialized)
+		if nil != e {
+			return 0, e
+		}
+		return int64(len(serialized)), p.Publish(subject, serialized)
+	}
+}
@@ -0,0 +1,127 @@
+package nats_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testMessage is a published message.
+type testMessage struct {
+	subject string
+	payload string
+}
+
+// testServer is an in-process fake NATS server.
+type testServer struct {
+	lock     sync.Mutex
+	messages []testMessage
+	connects int
+	pongs    int
+	down     bool
+	open     []net.Conn
+	addr     string
+}
+
+func testServerNew(t *testing.T) *testServer {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if nil != e {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	var s *testServer = &testServer{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, e := l.Accept()
+			if nil != e {
+				return
+			}
+			s.lock.Lock()
+			var down bool = s.down
+			if !down {
+				s.open = append(s.open, conn)
+			}
+			s.lock.Unlock()
+			if down {
+				_ = conn.Close()
+				continue
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// setDown closes open connections and rejects new connections while down.
+func (s *testServer) setDown(down bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.down = down
+	if !down {
+		return
+	}
+	for _, conn := range s.open {
+		_ = conn.Close()
+	}
+	s.open = nil
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	_, e := io.WriteString(conn, `INFO {"server_id":"test","max_payload":64}`+"\r\n")
+	if nil != e {
+		return
+	}
+	// the client must answer a PING at any time
+	_, e = io.WriteString(conn, "PING\r\n")
+	if nil != e {
+		return
+	}
+	var rdr *bufio.Reader = bufio.NewReader(conn)
+	for {
+		line, e := rdr.ReadString('\n')
+		if nil != e {
+			return
+		}
+		var fields []string = strings.Fields(line)
+		if 0 == len(fields) {
+			continue
+		}
+		var reply string
+		switch fields[0] {
+		case "CONNECT":
+			s.lock.Lock()
+			s.connects++
+			s.lock.Unlock()
+		case "PING":
+			reply = "PONG\r\n"
+		case "PONG":
+			s.lock.Lock()
+			s.pongs++
+			s.lock.Unlock()
+		case "PUB":
+			size, e := strconv.Atoi(fields[len(fields)-1])
+			if nil != e {
+				return
+			}
+			var payload []byte = make([]byte, size+2)
+			_, e = io.ReadFull(rdr, payload)
+			if nil != e {
+				return
+			}
+			s.lock.Lock()
+			s.messages = append(s.messages, testMessage{subject: fields[1], payload: string(payload[:size])})
+			s.lock.Unlock()
+		default:
+			reply = "-ERR 'Unknown Protocol Operation'\r\n"
+		}
+		_, e = io.WriteString(conn, reply)
+		if nil != e {
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package saver
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrBodyTooLarge is returned when a request body is larger than a limit.
+var ErrBodyTooLarge error = errors.New("request body too large")
+
+// RequestStdPrecheck inspects a standard(net/http) request without reading its body.
+//
+// A precheck can use the method, the url and the header only.
+type RequestStdPrecheck func(req *http.Request) error
+
+// RequestStdPrecheckAll creates a precheck which runs checks in order and stops at the first error.
+func RequestStdPrecheckAll(checks ...RequestStdPrecheck) RequestStdPrecheck {
+	return func(req *http.Request) error {
+		for _, check := range checks {
+			var e error = check(req)
+			if nil != e {
+				return e
+			}
+		}
+		return nil
+	}
+}
+
+// RequestStdPrecheckContentLengthNew creates a precheck which rejects large requests.
+//
+// Requests with an unknown length(chunked) are accepted;
+// RequestStdConvNew still limits the number of bytes to read.
+//
+// # Arguments
+//   - limit: Max Content-Length.
+func RequestStdPrecheckContentLengthNew(limit int64) RequestStdPrecheck {
+	return func(req *http.Request) error {
+		if limit < req.ContentLength {
+			return ErrBodyTooLarge
+		}
+		return nil
+	}
+}
+
+// RequestStdPrecheckLimiterNew creates a precheck from a RequestLimiter.
+//
+// # Arguments
+//   - l: Rejects too many requests.
+//   - limit: The limit passed to l.
+func RequestStdPrecheckLimiterNew[L any](l RequestLimiter[L], limit L) RequestStdPrecheck {
+	return func(_ *http.Request) error {
+		var tooMany bool = l(limit)
+		if tooMany {
+			return RequestLimiterErrTooMany
+		}
+		return nil
+	}
+}
+
+// PrecheckStatus gets a http status code for an error returned by a precheck.
+//
+// # Returns
+//   - The status of a rule of ErrorRulesDefault(sample: 413 for ErrBodyTooLarge).
+//   - 400 for other errors(a rejected request).
+func PrecheckStatus(e error) (status int) {
+	status, _ = ErrorRulesDefault.LookupOr(e, http.StatusBadRequest, "bad_request")
+	return status
+}
+
+// WithPrecheck creates a converter which runs a precheck before reading a request body.
+func (c RequestStdConv) WithPrecheck(check RequestStdPrecheck) RequestStdConv {
+	return func(req *http.Request) (q RequestStd, e error) {
+		e = check(req)
+		if nil != e {
+			return
+		}
+		return c(req)
+	}
+}
+
+// WithPrecheck creates a request saver which runs a precheck before the original saver.
+func (s RequestSaverStd[R]) WithPrecheck(check RequestStdPrecheck) RequestSaverStd[R] {
+	return func(req *http.Request) (result R, e error) {
+		e = check(req)
+		if nil != e {
+			return
+		}
+		return s(req)
+	}
+}
+
+// Handler creates a handler which rejects requests before the body is read.
+//
+// A rejected request gets a status from PrecheckStatus.
+// The body of a rejected request is never read, so a client which sent
+// "Expect: 100-continue" gets the final status without uploading the body.
+func (check RequestStdPrecheck) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, q *http.Request) {
+		var e error = check(q)
+		if nil == e {
+			next.ServeHTTP(w, q)
+			return
+		}
+		if 0 != q.ContentLength {
+			// the unread body must not be parsed as the next request
+			w.Header().Set("Connection", "close")
+		}
+		http.Error(w, e.Error(), PrecheckStatus(e))
+	})
+}
@@ -0,0 +1,125 @@
+package saver_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+type testPrecheckBody struct {
+	read bool
+	rdr  io.Reader
+}
+
+func (b *testPrecheckBody) Read(p []byte) (int, error) {
+	b.read = true
+	return b.rdr.Read(p)
+}
+
+func TestPrecheck(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RequestSaverStd", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("rejected before read", func(t *testing.T) {
+			t.Parallel()
+
+			var body *testPrecheckBody = &testPrecheckBody{rdr: strings.NewReader("hw")}
+			var q *http.Request = httptest.NewRequest("POST", "/", body)
+			q.ContentLength = 1024
+
+			var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+				return int64(len(serialized)), nil
+			}
+			var rs saver.RequestSaverStd[int64] = sav.NewRequestSaverStd(
+				saver.DupStdRequestSerializerNew(),
+			).WithPrecheck(saver.RequestStdPrecheckContentLengthNew(16))
+
+			_, e := rs(q)
+			t.Run("too large", assertTrue(errors.Is(e, saver.ErrBodyTooLarge)))
+			t.Run("not read", assertEq(body.read, false))
+		})
+
+		t.Run("accepted", func(t *testing.T) {
+			t.Parallel()
+
+			var q *http.Request = httptest.NewRequest("POST", "/", strings.NewReader("hw"))
+			var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+				return int64(len(serialized)), nil
+			}
+			var rs saver.RequestSaverStd[int64] = sav.NewRequestSaverStd(
+				saver.DupStdRequestSerializerNew(),
+			).WithPrecheck(saver.RequestStdPrecheckAll(
+				saver.RequestStdPrecheckContentLengthNew(16),
+				saver.RequestStdPrecheckLimiterNew(
+					func(_ int) (tooMany bool) { return false },
+					0,
+				),
+			))
+
+			cnt, e := rs(q)
+			t.Run("no error", assertNil(e))
+			t.Run("saved", assertEq(cnt, 2))
+		})
+	})
+
+	t.Run("PrecheckStatus", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("413", assertEq(saver.PrecheckStatus(saver.ErrBodyTooLarge), 413))
+		t.Run("429", assertEq(saver.PrecheckStatus(saver.RequestLimiterErrTooMany), 429))
+		t.Run("400", assertEq(saver.PrecheckStatus(errors.New("bad")), 400))
+		t.Run("quota", assertEq(saver.PrecheckStatus(saver.ErrQuotaExceeded), 429))
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("expect 100-continue", func(t *testing.T) {
+			t.Parallel()
+
+			var check saver.RequestStdPrecheck = saver.RequestStdPrecheckLimiterNew(
+				func(_ int) (tooMany bool) { return true },
+				0,
+			)
+			var svr *httptest.Server = httptest.NewServer(check.Handler(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(200)
+				}),
+			))
+			defer svr.Close()
+
+			conn, e := net.Dial("tcp", svr.Listener.Addr().String())
+			t.Run("no dial error", assertNil(e))
+			defer conn.Close()
+
+			_, e = io.WriteString(conn, strings.Join([]string{
+				"POST /api/v1/write HTTP/1.1",
+				"Host: localhost",
+				"Content-Length: 1048576",
+				"Expect: 100-continue",
+				"",
+				"",
+			}, "\r\n"))
+			t.Run("no write error", assertNil(e))
+
+			res, e := http.ReadResponse(bufio.NewReader(conn), nil)
+			t.Run("no read error", assertNil(e))
+			defer res.Body.Close()
+
+			t.Run("final status", assertEq(res.StatusCode, 429))
+			var buf bytes.Buffer
+			_, _ = io.Copy(&buf, res.Body)
+			t.Run("error message", assertTrue(0 < buf.Len()))
+		})
+	})
+}
@@ -0,0 +1,151 @@
+package saver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type proxyRecordKey struct{}
+
+// proxyRecord keeps a captured request until its response is captured.
+type proxyRecord struct {
+	once     sync.Once
+	exchange ExchangeStd
+	received time.Time
+}
+
+// captureBody wraps a response body and records up to limit bytes.
+type captureBody struct {
+	io.ReadCloser
+	limit    int64
+	captured bytes.Buffer
+	onClose  func(captured []byte)
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, e := b.ReadCloser.Read(p)
+	var rest int64 = b.limit - int64(b.captured.Len())
+	switch {
+	case rest <= 0:
+	case int64(n) <= rest:
+		_, _ = b.captured.Write(p[:n]) // always nil error
+	default:
+		_, _ = b.captured.Write(p[:rest]) // always nil error
+	}
+	return n, e
+}
+
+func (b *captureBody) Close() error {
+	var e error = b.ReadCloser.Close()
+	b.onClose(b.captured.Bytes())
+	return e
+}
+
+// RecordingProxy is a reverse proxy which saves request/response pairs.
+//
+// Exchanges are queued after responses are sent; saving them is not on the critical path.
+type RecordingProxy struct {
+	// Proxy forwards requests; its Transport can be replaced.
+	Proxy *httputil.ReverseProxy
+
+	enqueue      func(x ExchangeStd) error
+	requestLimit int64
+	idGen        RecordIDGen
+	onError      func(e error)
+}
+
+// RecordingProxyNew creates a recording reverse proxy.
+//
+// # Arguments
+//   - upstream: The base url of an upstream.
+//   - enqueue: Queues an exchange without blocking(sample: RequestSaverAsync.Enqueue).
+//   - requestLimit: Number of request body bytes to save.
+//   - responseLimit: Number of response body bytes to save.
+//   - idGen: Creates a record id.
+//   - onError: Reports proxy errors and enqueue errors.
+func RecordingProxyNew(
+	upstream *url.URL,
+	enqueue func(x ExchangeStd) error,
+	requestLimit, responseLimit int64,
+	idGen RecordIDGen,
+	onError func(e error),
+) *RecordingProxy {
+	var p *RecordingProxy = &RecordingProxy{
+		enqueue:      enqueue,
+		requestLimit: requestLimit,
+		idGen:        idGen,
+		onError:      onError,
+	}
+	p.Proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			pr.SetXForwarded()
+		},
+		ModifyResponse: func(res *http.Response) error {
+			rec, found := res.Request.Context().Value(proxyRecordKey{}).(*proxyRecord)
+			if !found {
+				return nil
+			}
+			var status int = res.StatusCode
+			var header http.Header = res.Header.Clone()
+			if http.StatusSwitchingProtocols == status {
+				// the proxy needs the writable body of an upgraded connection; its stream is not saved
+				p.finish(rec, &ResponseStd{Status: status, Header: header})
+				return nil
+			}
+			res.Body = &captureBody{
+				ReadCloser: res.Body,
+				limit:      responseLimit,
+				onClose: func(captured []byte) {
+					p.finish(rec, &ResponseStd{
+						Status: status,
+						Header: header,
+						Body:   bytes.Clone(captured),
+					})
+				},
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, q *http.Request, e error) {
+			onError(e)
+			w.WriteHeader(http.StatusBadGateway)
+			rec, found := q.Context().Value(proxyRecordKey{}).(*proxyRecord)
+			if found {
+				p.finish(rec, &ResponseStd{Status: http.StatusBadGateway})
+			}
+		},
+	}
+	return p
+}
+
+// finish queues an exchange once.
+func (p *RecordingProxy) finish(rec *proxyRecord, res *ResponseStd) {
+	rec.once.Do(func() {
+		res.Latency = time.Since(rec.received)
+		rec.exchange.Response = res
+		var e error = p.enqueue(rec.exchange)
+		if nil != e {
+			p.onError(e)
+		}
+	})
+}
+
+func (p *RecordingProxy) ServeHTTP(w http.ResponseWriter, q *http.Request) {
+	var received time.Time = time.Now()
+	captured, e := bodyCapture(q, p.requestLimit)
+	if nil != e {
+		p.onError(e)
+	}
+	var rec *proxyRecord = &proxyRecord{
+		exchange: ExchangeStdNew(q, captured, p.idGen(), received),
+		received: received,
+	}
+	var ctx context.Context = context.WithValue(q.Context(), proxyRecordKey{}, rec)
+	p.Proxy.ServeHTTP(w, q.WithContext(ctx))
+}
@@ -0,0 +1,160 @@
+package saver_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RecordingProxy", func(t *testing.T) {
+		t.Parallel()
+
+		var upstream *httptest.Server = httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, q *http.Request) {
+				body, _ := io.ReadAll(q.Body)
+				w.Header().Set("X-Upstream", "1")
+				w.WriteHeader(201)
+				_, _ = w.Write([]byte("echo:" + string(body)))
+			},
+		))
+		defer upstream.Close()
+
+		target, e := url.Parse(upstream.URL)
+		t.Run("no parse error", assertNil(e))
+
+		var exchanges chan saver.ExchangeStd = make(chan saver.ExchangeStd, 1)
+		var async *saver.RequestSaverAsync[saver.ExchangeStd, int] = saver.RequestSaverAsyncNew(
+			func(x saver.ExchangeStd) (int, error) {
+				exchanges <- x
+				return 1, nil
+			},
+			1,
+			16,
+			func(_ saver.ExchangeStd, _ int, e error) {},
+		)
+		defer async.Close()
+
+		var proxy *saver.RecordingProxy = saver.RecordingProxyNew(
+			target,
+			async.Enqueue,
+			1024,
+			1024,
+			saver.RecordIDGenRandom,
+			func(e error) { t.Errorf("unexpected error: %v", e) },
+		)
+		var front *httptest.Server = httptest.NewServer(proxy)
+		defer front.Close()
+
+		res, e := http.Post(front.URL+"/hook?x=1", "text/plain", strings.NewReader("hw"))
+		t.Run("no request error", assertNil(e))
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		t.Run("proxied status", assertEq(res.StatusCode, 201))
+		t.Run("proxied body", assertEq(string(body), "echo:hw"))
+
+		var x saver.ExchangeStd = <-exchanges
+		var req saver.Request[http.Header, []byte] = saver.Request[http.Header, []byte](x.Request)
+		t.Run("uri", assertEq(x.URI, "/hook?x=1"))
+		t.Run("request body", assertEq(string(req.Body()), "hw"))
+		t.Run("status", assertEq(x.Response.Status, 201))
+		t.Run("response header", assertEq(x.Response.Header.Get("X-Upstream"), "1"))
+		t.Run("response body", assertEq(string(x.Response.Body), "echo:hw"))
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		t.Parallel()
+
+		var upstream *httptest.Server = httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, q *http.Request) {
+				conn, rw, e := w.(http.Hijacker).Hijack()
+				if nil != e {
+					return
+				}
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+				_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+				_ = rw.Flush()
+				line, _ := rw.ReadString('\n')
+				_, _ = rw.WriteString("echo:" + line)
+				_ = rw.Flush()
+			},
+		))
+		defer upstream.Close()
+		target, _ := url.Parse(upstream.URL)
+
+		var exchanges chan saver.ExchangeStd = make(chan saver.ExchangeStd, 1)
+		var proxy *saver.RecordingProxy = saver.RecordingProxyNew(
+			target,
+			func(x saver.ExchangeStd) error {
+				exchanges <- x
+				return nil
+			},
+			1024,
+			1024,
+			saver.RecordIDGenRandom,
+			func(e error) { t.Errorf("unexpected error: %v", e) },
+		)
+		var front *httptest.Server = httptest.NewServer(proxy)
+		defer front.Close()
+
+		conn, e := net.Dial("tcp", front.Listener.Addr().String())
+		t.Run("no dial error", assertNil(e))
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: front\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+		var rdr *bufio.Reader = bufio.NewReader(conn)
+		res, e := http.ReadResponse(rdr, nil)
+		t.Run("no response error", assertNil(e))
+		t.Run("switched", assertEq(res.StatusCode, 101))
+
+		_, _ = conn.Write([]byte("hw\n"))
+		line, _ := rdr.ReadString('\n')
+		t.Run("upgraded stream", assertEq(line, "echo:hw\n"))
+
+		var x saver.ExchangeStd = <-exchanges
+		t.Run("status", assertEq(x.Response.Status, 101))
+		t.Run("response header", assertEq(x.Response.Header.Get("Upgrade"), "echo"))
+		t.Run("no response body", assertEq(len(x.Response.Body), 0))
+	})
+
+	t.Run("RequestSaverAsync", func(t *testing.T) {
+		t.Parallel()
+
+		var block chan struct{} = make(chan struct{})
+		var async *saver.RequestSaverAsync[uint8, int] = saver.RequestSaverAsyncNew(
+			func(_ uint8) (int, error) {
+				<-block
+				return 1, nil
+			},
+			1,
+			1,
+			func(_ uint8, _ int, _ error) {},
+		)
+		var sav saver.RequestSaver[uint8, bool] = async.AsRequestSaver()
+
+		var queued int
+		for i := 0; i < 3; i++ {
+			ok, _ := sav(0)
+			if ok {
+				queued++
+			}
+		}
+		t.Run("queue full", assertTrue(queued < 3))
+
+		close(block)
+		async.Close()
+		_, e := sav(0)
+		t.Run("closed", assertEq(e, saver.ErrQueueClosed))
+	})
+}
@@ -0,0 +1,304 @@
+package saver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a tenant used up its quota.
+var ErrQuotaExceeded error = errors.New("quota exceeded")
+
+// TenantResolver gets a tenant from a standard(net/http) request.
+type TenantResolver func(req *http.Request) (tenant string)
+
+// TenantResolverHeaderNew creates a resolver which gets a tenant from a header.
+func TenantResolverHeaderNew(key string) TenantResolver {
+	return func(req *http.Request) (tenant string) { return req.Header.Get(key) }
+}
+
+// TenantResolverHost gets a tenant from the Host(without a port).
+var TenantResolverHost TenantResolver = func(req *http.Request) (tenant string) {
+	host, _, e := net.SplitHostPort(req.Host)
+	if nil != e {
+		return req.Host
+	}
+	return host
+}
+
+// QuotaPeriod gets a period key from a time; counters are reset when the key changes.
+type QuotaPeriod func(t time.Time) (period string)
+
+// QuotaPeriodDaily resets counters every day(UTC).
+var QuotaPeriodDaily QuotaPeriod = func(t time.Time) (period string) { return t.UTC().Format("2006-01-02") }
+
+// QuotaPeriodMonthly resets counters every month(UTC).
+var QuotaPeriodMonthly QuotaPeriod = func(t time.Time) (period string) { return t.UTC().Format("2006-01") }
+
+// QuotaLimit is a limit for a period; 0 means unlimited.
+//
+// Bytes are request body bytes(not serialized bytes); framing of serializers is not counted.
+type QuotaLimit struct {
+	Requests int64
+	Bytes    int64
+}
+
+// QuotaLimits are limits of tenants.
+type QuotaLimits struct {
+	Default QuotaLimit
+	Tenants map[string]QuotaLimit
+}
+
+// Get gets the limit of a tenant or the default limit.
+func (l QuotaLimits) Get(tenant string) QuotaLimit {
+	limit, found := l.Tenants[tenant]
+	if found {
+		return limit
+	}
+	return l.Default
+}
+
+// QuotaUsage is a usage of a tenant in a period.
+type QuotaUsage struct {
+	Period   string `json:"period"`
+	Requests int64  `json:"requests"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// exceeded checks a usage with bytes of running saves and the declared bytes of a request.
+func (u QuotaUsage) exceeded(limit QuotaLimit, runningBytes, incomingBytes int64) bool {
+	var tooManyRequests bool = 0 < limit.Requests && limit.Requests <= u.Requests
+	var used int64 = u.Bytes + runningBytes
+	var tooManyBytes bool = 0 < limit.Bytes && (limit.Bytes <= used || limit.Bytes < used+incomingBytes)
+	return tooManyRequests || tooManyBytes
+}
+
+// quotaBody is a request body which counts read bytes and fails with ErrQuotaExceeded after the bytes left.
+type quotaBody struct {
+	io.ReadCloser
+	left int64 // -1: unlimited
+	read int64
+}
+
+func (b *quotaBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		n, e := b.ReadCloser.Read(p)
+		b.read += int64(n)
+		return n, e
+	}
+	if b.left+1 < int64(len(p)) {
+		p = p[:b.left+1]
+	}
+	n, e := b.ReadCloser.Read(p)
+	if b.left < int64(n) {
+		n = int(b.left)
+		b.left = 0
+		b.read += int64(n)
+		return n, ErrQuotaExceeded
+	}
+	b.left -= int64(n)
+	b.read += int64(n)
+	return n, e
+}
+
+// QuotaStore keeps usage counters of tenants and persists them to a file.
+type QuotaStore struct {
+	// PersistInterval is the min interval of writes of the file(0: after every save); see Flush.
+	PersistInterval time.Duration
+
+	lock      sync.Mutex
+	filename  string
+	period    QuotaPeriod
+	now       func() time.Time
+	usage     map[string]QuotaUsage
+	running   map[string]int64 // reserved bytes of running saves
+	persisted time.Time
+	dirty     bool
+}
+
+// QuotaStoreNewFile creates a quota store which loads/saves counters from/to a file.
+//
+// # Arguments
+//   - filename: A JSON file to keep counters(created if missing).
+//   - period: Gets a period key.
+//   - now: Gets the current time.
+func QuotaStoreNewFile(filename string, period QuotaPeriod, now func() time.Time) (*QuotaStore, error) {
+	var s QuotaStore = QuotaStore{
+		filename: filename,
+		period:   period,
+		now:      now,
+		usage:    make(map[string]QuotaUsage),
+		running:  make(map[string]int64),
+	}
+	s.persisted = now() // the loaded file is up to date
+	serialized, e := os.ReadFile(filename)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+		return &s, nil
+	case nil != e:
+		return nil, e
+	default:
+		return &s, json.Unmarshal(serialized, &s.usage)
+	}
+}
+
+func (s *QuotaStore) current(tenant string) QuotaUsage {
+	var period string = s.period(s.now())
+	var u QuotaUsage = s.usage[tenant]
+	if period != u.Period {
+		return QuotaUsage{Period: period}
+	}
+	return u
+}
+
+func (s *QuotaStore) persist() error {
+	serialized, e := json.Marshal(s.usage)
+	if nil != e {
+		return e
+	}
+	var tmp string = s.filename + ".tmp"
+	e = os.WriteFile(tmp, serialized, 0644)
+	if nil != e {
+		return e
+	}
+	e = os.Rename(tmp, s.filename)
+	if nil != e {
+		return e
+	}
+	s.persisted = s.now()
+	s.dirty = false
+	return nil
+}
+
+// Flush writes counters which are not written yet(sample: a periodic call when PersistInterval is set).
+func (s *QuotaStore) Flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.persist()
+}
+
+// Usage gets the usage of a tenant in the current period.
+func (s *QuotaStore) Usage(tenant string) QuotaUsage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.current(tenant)
+}
+
+// reserve counts a request and reserves its bytes if the tenant has not used up its quota.
+//
+// A request of unknown length(incomingBytes < 0) reserves every byte left of a limited tenant;
+// the unused part is released by commit.
+//
+// The result is the number of reserved bytes(the max body size if the bytes are limited).
+func (s *QuotaStore) reserve(tenant string, limit QuotaLimit, incomingBytes int64) (reserved int64, e error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var u QuotaUsage = s.current(tenant)
+	var running int64 = s.running[tenant]
+	reserved = incomingBytes
+	if reserved < 0 {
+		reserved = 0
+	}
+	if u.exceeded(limit, running, reserved) {
+		return 0, ErrQuotaExceeded
+	}
+	if incomingBytes < 0 && 0 < limit.Bytes {
+		reserved = limit.Bytes - u.Bytes - running
+	}
+	u.Requests += 1
+	s.usage[tenant] = u
+	s.running[tenant] = running + reserved
+	return reserved, nil
+}
+
+// commit releases reserved bytes, counts body bytes(or cancels a reservation) and persists counters.
+func (s *QuotaStore) commit(tenant string, reservedBytes, bodyBytes int64, saved bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.running[tenant] -= reservedBytes
+	if s.running[tenant] <= 0 {
+		delete(s.running, tenant)
+	}
+	var u QuotaUsage = s.current(tenant)
+	switch saved {
+	case true:
+		u.Bytes += bodyBytes
+	default:
+		if 0 < u.Requests {
+			u.Requests -= 1
+		}
+	}
+	s.usage[tenant] = u
+	s.dirty = true
+	if 0 < s.PersistInterval && s.now().Sub(s.persisted) < s.PersistInterval {
+		return nil
+	}
+	return s.persist()
+}
+
+// Precheck creates a precheck which rejects tenants without a quota left.
+//
+// The declared Content-Length is checked against the byte quota.
+func (s *QuotaStore) Precheck(resolver TenantResolver, limits QuotaLimits) RequestStdPrecheck {
+	return func(req *http.Request) error {
+		var tenant string = resolver(req)
+		var incoming int64 = req.ContentLength
+		if incoming < 0 {
+			incoming = 0
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.current(tenant).exceeded(limits.Get(tenant), s.running[tenant], incoming) {
+			return ErrQuotaExceeded
+		}
+		return nil
+	}
+}
+
+// QuotaLimitedNew creates a request saver builder which enforces quotas of tenants.
+//
+// A request and its declared bytes(Content-Length) are reserved before it is saved;
+// read body bytes are counted after it is saved(see QuotaLimit).
+// A request of unknown length(chunked) reserves every byte left of its tenant while it is saved,
+// so concurrent requests of the tenant are rejected until then.
+// A request body is read up to the reserved bytes(ErrQuotaExceeded).
+// Counters are persisted after every save(see PersistInterval).
+//
+// # Arguments
+//   - store: Keeps counters.
+//   - resolver: Gets a tenant from a request.
+func QuotaLimitedNew(
+	store *QuotaStore,
+	resolver TenantResolver,
+) RequestSaverLimitedBuilder[*http.Request, int64, QuotaLimits] {
+	return func(limits QuotaLimits) func(RequestSaver[*http.Request, int64]) RequestSaver[*http.Request, int64] {
+		return func(original RequestSaver[*http.Request, int64]) RequestSaver[*http.Request, int64] {
+			return func(req *http.Request) (bytesCount int64, e error) {
+				var tenant string = resolver(req)
+				var limit QuotaLimit = limits.Get(tenant)
+				reserved, e := store.reserve(tenant, limit, req.ContentLength)
+				if nil != e {
+					return 0, e
+				}
+				var body *quotaBody = &quotaBody{ReadCloser: req.Body, left: -1}
+				if 0 < limit.Bytes {
+					body.left = reserved
+				}
+				if nil != req.Body {
+					req.Body = body
+				}
+				bytesCount, e = original(req)
+				return bytesCount, errors.Join(e, store.commit(tenant, reserved, body.read, nil == e))
+			}
+		}
+	}
+}
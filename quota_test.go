@@ -0,0 +1,172 @@
+package saver_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	t.Run("QuotaLimitedNew", func(t *testing.T) {
+		t.Parallel()
+
+		var filename string = filepath.Join(t.TempDir(), "quota.json")
+		var now time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		var clock func() time.Time = func() time.Time { return now }
+
+		store, e := saver.QuotaStoreNewFile(filename, saver.QuotaPeriodDaily, clock)
+		t.Run("no store error", assertNil(e))
+
+		var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+			return int64(len(serialized)), nil
+		}
+		var original saver.RequestSaverStd[int64] = sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew())
+		var limited saver.RequestSaver[*http.Request, int64] = saver.QuotaLimitedNew(
+			store,
+			saver.TenantResolverHeaderNew("X-Tenant"),
+		)(saver.QuotaLimits{
+			Default: saver.QuotaLimit{Requests: 2},
+			Tenants: map[string]saver.QuotaLimit{"big": {Bytes: 1024}},
+		})(saver.RequestSaver[*http.Request, int64](original))
+
+		var request func(tenant string, body []byte) *http.Request = func(tenant string, body []byte) *http.Request {
+			var q *http.Request = httptest.NewRequest("POST", "/", bytes.NewReader(body))
+			q.Header.Set("X-Tenant", tenant)
+			return q
+		}
+
+		_, e = limited(request("small", []byte("hw")))
+		t.Run("1st", assertNil(e))
+		_, e = limited(request("small", []byte("hw")))
+		t.Run("2nd", assertNil(e))
+		_, e = limited(request("small", []byte("hw")))
+		t.Run("3rd", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+
+		_, e = limited(request("big", make([]byte, 1000)))
+		t.Run("big 1st", assertNil(e))
+		_, e = limited(request("big", make([]byte, 100)))
+		t.Run("big 2nd", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+
+		reloaded, e := saver.QuotaStoreNewFile(filename, saver.QuotaPeriodDaily, clock)
+		t.Run("no reload error", assertNil(e))
+		t.Run("persisted requests", assertEq(reloaded.Usage("small").Requests, 2))
+		t.Run("persisted bytes", assertEq(reloaded.Usage("big").Bytes, 1000))
+
+		var check saver.RequestStdPrecheck = reloaded.Precheck(
+			saver.TenantResolverHeaderNew("X-Tenant"),
+			saver.QuotaLimits{Default: saver.QuotaLimit{Requests: 2}},
+		)
+		t.Run("precheck", assertTrue(errors.Is(check(request("small", nil)), saver.ErrQuotaExceeded)))
+
+		now = now.Add(24 * time.Hour)
+		t.Run("reset", assertEq(reloaded.Usage("small").Requests, 0))
+		t.Run("precheck next day", assertNil(check(request("small", nil))))
+	})
+
+	t.Run("hard byte caps", func(t *testing.T) {
+		t.Parallel()
+
+		var now time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		var filename string = filepath.Join(t.TempDir(), "quota.json")
+		store, _ := saver.QuotaStoreNewFile(filename, saver.QuotaPeriodDaily, func() time.Time { return now })
+		store.PersistInterval = time.Hour
+
+		var release chan struct{} = make(chan struct{})
+		var hold chan struct{} = make(chan struct{})
+		var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+			if strings.HasPrefix(string(serialized), "block") {
+				<-release
+			}
+			if strings.HasPrefix(string(serialized), "hold") {
+				<-hold
+			}
+			return int64(len(serialized)) + 1536, nil // framing is not charged
+		}
+		var original saver.RequestSaverStd[int64] = sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew())
+		var limited saver.RequestSaver[*http.Request, int64] = saver.QuotaLimitedNew(
+			store,
+			saver.TenantResolverHeaderNew("X-Tenant"),
+		)(saver.QuotaLimits{
+			Default: saver.QuotaLimit{Bytes: 1000},
+			Tenants: map[string]saver.QuotaLimit{"exact": {Bytes: 4}, "concurrent": {Bytes: 100}},
+		})(saver.RequestSaver[*http.Request, int64](original))
+
+		var request func(tenant, body string, chunked bool) *http.Request = func(
+			tenant, body string,
+			chunked bool,
+		) *http.Request {
+			var q *http.Request = httptest.NewRequest("POST", "/", strings.NewReader(body))
+			q.Header.Set("X-Tenant", tenant)
+			if chunked {
+				q.ContentLength = -1
+			}
+			return q
+		}
+
+		_, e := limited(request("exact", "abcd", false))
+		t.Run("up to the limit", assertNil(e))
+		_, e = limited(request("exact", "", false))
+		t.Run("at the limit", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+
+		_, e = limited(request("chunked", strings.Repeat("x", 1001), true))
+		t.Run("chunked body capped", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+		t.Run("not counted", assertEq(store.Usage("chunked").Bytes, 0))
+
+		var done chan error = make(chan error)
+		go func() {
+			_, e := limited(request("running", "block"+strings.Repeat("x", 595), false))
+			done <- e
+		}()
+		for 0 == store.Usage("running").Requests {
+			time.Sleep(time.Millisecond)
+		}
+		_, e = limited(request("running", strings.Repeat("x", 600), false))
+		t.Run("running bytes reserved", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+		close(release)
+		t.Run("running save", assertNil(<-done))
+		t.Run("saved bytes", assertEq(store.Usage("running").Bytes, 600))
+
+		var chunked chan error = make(chan error)
+		go func() {
+			_, e := limited(request("concurrent", "hold"+strings.Repeat("x", 86), true))
+			chunked <- e
+		}()
+		for 0 == store.Usage("concurrent").Requests {
+			time.Sleep(time.Millisecond)
+		}
+		for i := 0; i < 4; i++ {
+			_, e = limited(request("concurrent", strings.Repeat("x", 90), true))
+			t.Run("bytes left reserved by a chunked save", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+		}
+		close(hold)
+		t.Run("chunked save", assertNil(<-chunked))
+		t.Run("body bytes", assertEq(store.Usage("concurrent").Bytes, 90))
+		_, e = limited(request("concurrent", strings.Repeat("x", 10), true))
+		t.Run("unused bytes released", assertNil(e))
+		_, e = limited(request("concurrent", "x", true))
+		t.Run("at the limit after release", assertTrue(errors.Is(e, saver.ErrQuotaExceeded)))
+
+		_, e = os.Stat(filename)
+		t.Run("not persisted yet", assertTrue(errors.Is(e, os.ErrNotExist)))
+		t.Run("no flush error", assertNil(store.Flush()))
+		reloaded, _ := saver.QuotaStoreNewFile(filename, saver.QuotaPeriodDaily, func() time.Time { return now })
+		t.Run("flushed", assertEq(reloaded.Usage("exact").Bytes, 4))
+	})
+
+	t.Run("TenantResolverHost", func(t *testing.T) {
+		t.Parallel()
+
+		var q *http.Request = httptest.NewRequest("POST", "http://tenant.example.com:8080/", nil)
+		t.Run("no port", assertEq(saver.TenantResolverHost(q), "tenant.example.com"))
+	})
+}
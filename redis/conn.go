@@ -0,0 +1,181 @@
+// Package redis provides a small RESP2 client and BytesSaver backends for Redis.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrProtocol is returned when a reply is not a valid RESP2 reply.
+var ErrProtocol error = errors.New("invalid RESP reply")
+
+// Error is an error reply sent by a server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Reply is a RESP2 reply.
+type Reply struct {
+	// Kind is the type byte of the reply('+', '-', ':', '$' or '*').
+	Kind  byte
+	Str   []byte
+	Int   int64
+	Array []Reply
+	Nil   bool
+}
+
+// Command is a command and its arguments(sample: LPUSH key value).
+type Command [][]byte
+
+// CommandNew creates a command.
+func CommandNew(name string, args ...[]byte) Command {
+	return append(Command{[]byte(name)}, args...)
+}
+
+// Conn is a connection to a RESP2 server.
+type Conn struct {
+	conn    net.Conn
+	rdr     *bufio.Reader
+	wtr     *bufio.Writer
+	timeout time.Duration
+}
+
+// ConnNew creates a connection from a net.Conn.
+//
+// # Arguments
+//   - conn: A connected stream(tcp or unix).
+//   - timeout: Deadline of each round trip(0: no deadline).
+func ConnNew(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{
+		conn:    conn,
+		rdr:     bufio.NewReader(conn),
+		wtr:     bufio.NewWriter(conn),
+		timeout: timeout,
+	}
+}
+
+// Dial connects to a server.
+func Dial(network, address string, timeout time.Duration) (*Conn, error) {
+	conn, e := net.DialTimeout(network, address, timeout)
+	if nil != e {
+		return nil, e
+	}
+	return ConnNew(conn, timeout), nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error { return c.conn.Close() }
+
+func (c *Conn) writeCommand(cmd Command) {
+	_, _ = fmt.Fprintf(c.wtr, "*%d\r\n", len(cmd)) // errors are returned by Flush
+	for _, arg := range cmd {
+		_, _ = fmt.Fprintf(c.wtr, "$%d\r\n", len(arg))
+		_, _ = c.wtr.Write(arg)
+		_, _ = c.wtr.WriteString("\r\n")
+	}
+}
+
+func (c *Conn) readLine() ([]byte, error) {
+	line, e := c.rdr.ReadSlice('\n')
+	if nil != e {
+		return nil, e
+	}
+	if len(line) < 3 || '\r' != line[len(line)-2] {
+		return nil, ErrProtocol
+	}
+	return line[:len(line)-2], nil
+}
+
+func (c *Conn) readReply() (r Reply, e error) {
+	line, e := c.readLine()
+	if nil != e {
+		return r, e
+	}
+	r.Kind = line[0]
+	var rest []byte = line[1:]
+	switch r.Kind {
+	case '+', '-':
+		r.Str = append([]byte(nil), rest...)
+		return r, nil
+	case ':':
+		r.Int, e = strconv.ParseInt(string(rest), 10, 64)
+		return r, e
+	case '$':
+		var size int
+		size, e = strconv.Atoi(string(rest))
+		if nil != e || size < 0 {
+			r.Nil = true
+			return r, e
+		}
+		r.Str = make([]byte, size+2)
+		_, e = io.ReadFull(c.rdr, r.Str)
+		r.Str = r.Str[:size]
+		return r, e
+	case '*':
+		var size int
+		size, e = strconv.Atoi(string(rest))
+		if nil != e || size < 0 {
+			r.Nil = true
+			return r, e
+		}
+		r.Array = make([]Reply, size)
+		for i := range r.Array {
+			r.Array[i], e = c.readReply()
+			if nil != e {
+				return r, e
+			}
+		}
+		return r, nil
+	default:
+		return r, ErrProtocol
+	}
+}
+
+// Pipeline sends commands and reads their replies.
+//
+// # Returns
+//   - Replies of all commands.
+//   - The first error reply as an Error.
+//   - A network error or ErrProtocol(the connection should be closed).
+func (c *Conn) Pipeline(cmds ...Command) (replies []Reply, e error) {
+	if 0 < c.timeout {
+		e = c.conn.SetDeadline(time.Now().Add(c.timeout))
+		if nil != e {
+			return nil, e
+		}
+	}
+	for _, cmd := range cmds {
+		c.writeCommand(cmd)
+	}
+	e = c.wtr.Flush()
+	if nil != e {
+		return nil, e
+	}
+	replies = make([]Reply, 0, len(cmds))
+	var replyErr error
+	for range cmds {
+		r, e := c.readReply()
+		if nil != e {
+			return replies, e
+		}
+		if '-' == r.Kind && nil == replyErr {
+			replyErr = Error(r.Str)
+		}
+		replies = append(replies, r)
+	}
+	return replies, replyErr
+}
+
+// Do sends a command and reads its reply.
+func (c *Conn) Do(name string, args ...[]byte) (Reply, error) {
+	replies, e := c.Pipeline(CommandNew(name, args...))
+	if len(replies) < 1 {
+		return Reply{}, e
+	}
+	return replies[0], e
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed is returned when a pool is already closed.
+var ErrPoolClosed error = errors.New("pool closed")
+
+// Pool keeps idle connections.
+type Pool struct {
+	lock   sync.Mutex
+	closed bool
+	dial   func() (*Conn, error)
+	idle   chan *Conn
+}
+
+// PoolNew creates a connection pool.
+//
+// # Arguments
+//   - dial: Creates a new connection.
+//   - maxIdle: Max number of idle connections to keep.
+func PoolNew(dial func() (*Conn, error), maxIdle int) *Pool {
+	return &Pool{
+		dial: dial,
+		idle: make(chan *Conn, maxIdle),
+	}
+}
+
+// Get gets an idle connection or creates a new connection.
+func (p *Pool) Get() (*Conn, error) {
+	p.lock.Lock()
+	var closed bool = p.closed
+	p.lock.Unlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+		return p.dial()
+	}
+}
+
+// Put returns a connection; a broken connection is closed.
+func (p *Pool) Put(c *Conn, broken bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if broken || p.closed {
+		_ = c.Close() // nothing to do with the error
+		return
+	}
+	select {
+	case p.idle <- c:
+	default:
+		_ = c.Close() // too many idle connections
+	}
+}
+
+// Pipeline sends commands using a pooled connection.
+func (p *Pool) Pipeline(cmds ...Command) ([]Reply, error) {
+	c, e := p.Get()
+	if nil != e {
+		return nil, e
+	}
+	replies, e := c.Pipeline(cmds...)
+	var replyErr Error
+	p.Put(c, nil != e && !errors.As(e, &replyErr))
+	return replies, e
+}
+
+// Do sends a command using a pooled connection.
+func (p *Pool) Do(name string, args ...[]byte) (Reply, error) {
+	replies, e := p.Pipeline(CommandNew(name, args...))
+	if len(replies) < 1 {
+		return Reply{}, e
+	}
+	return replies[0], e
+}
+
+// Close closes idle connections; connections in use are closed when they are returned.
+func (p *Pool) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	var e error
+	for {
+		select {
+		case c := <-p.idle:
+			e = errors.Join(e, c.Close())
+		default:
+			return e
+		}
+	}
+}
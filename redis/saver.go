@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// ErrEmptyKey is returned when a record has no key.
+var ErrEmptyKey error = errors.New("empty key")
+
+// Push selects a command to push to a list.
+type Push string
+
+const (
+	// PushLeft pushes to the head(newest first).
+	PushLeft Push = "LPUSH"
+
+	// PushRight pushes to the tail(oldest first).
+	PushRight Push = "RPUSH"
+)
+
+// ListSaverNew creates a saver which pushes serialized requests to a list.
+//
+// # Arguments
+//   - p: Provides connections.
+//   - key: The key of a list.
+//   - push: PushLeft or PushRight.
+//   - maxLen: Keeps the newest maxLen items using LTRIM(0: no limit).
+func ListSaverNew(p *Pool, key []byte, push Push, maxLen int64) saver.BytesSaver {
+	var trim Command
+	switch {
+	case maxLen <= 0:
+	case PushLeft == push:
+		trim = CommandNew("LTRIM", key, []byte("0"), []byte(strconv.FormatInt(maxLen-1, 10)))
+	default:
+		trim = CommandNew("LTRIM", key, []byte(strconv.FormatInt(-maxLen, 10)), []byte("-1"))
+	}
+	return func(serialized []byte) (bytesCount int64, e error) {
+		var cmds []Command = []Command{CommandNew(string(push), key, serialized)}
+		if nil != trim {
+			cmds = append(cmds, trim)
+		}
+		_, e = p.Pipeline(cmds...)
+		return int64(len(serialized)), e
+	}
+}
+
+// StreamSaverNew creates a saver which adds serialized requests to a stream using XADD.
+//
+// # Arguments
+//   - p: Provides connections.
+//   - key: The key of a stream.
+//   - field: The field name of a serialized request.
+//   - maxLen: Caps the stream approximately(MAXLEN ~; 0: no limit).
+func StreamSaverNew(p *Pool, key, field []byte, maxLen int64) saver.BytesSaver {
+	var prefix [][]byte = [][]byte{key}
+	if 0 < maxLen {
+		prefix = append(prefix, []byte("MAXLEN"), []byte("~"), []byte(strconv.FormatInt(maxLen, 10)))
+	}
+	prefix = append(prefix, []byte("*"), field)
+	return func(serialized []byte) (bytesCount int64, e error) {
+		var args [][]byte = append(append([][]byte(nil), prefix...), serialized)
+		_, e = p.Do("XADD", args...)
+		return int64(len(serialized)), e
+	}
+}
+
+// KeyRecordIDNew creates a key func which gets a key from the record id of a serialized exchange.
+//
+// # Arguments
+//   - prefix: The prefix of keys(sample: "req:").
+func KeyRecordIDNew(prefix string) func(serialized []byte) (key []byte, e error) {
+	return func(serialized []byte) (key []byte, e error) {
+		x, e := saver.ExchangeStdFromTar(serialized)
+		if nil != e {
+			return nil, e
+		}
+		if "" == x.ID {
+			return nil, ErrEmptyKey
+		}
+		return []byte(prefix + x.ID), nil
+	}
+}
+
+// SetSaverNew creates a saver which saves each serialized request using SET.
+//
+// # Arguments
+//   - p: Provides connections.
+//   - keyOf: Gets a key of a serialized request(sample: KeyRecordIDNew).
+//   - ttl: Expiration(PX; sub-millisecond ttls are rounded up to 1 ms; 0: no expiration).
+func SetSaverNew(
+	p *Pool,
+	keyOf func(serialized []byte) (key []byte, e error),
+	ttl time.Duration,
+) saver.BytesSaver {
+	return func(serialized []byte) (bytesCount int64, e error) {
+		key, e := keyOf(serialized)
+		if nil != e {
+			return 0, e
+		}
+		var args [][]byte = [][]byte{key, serialized}
+		if 0 < ttl {
+			var px int64 = ttl.Milliseconds()
+			if px < 1 {
+				px = 1 // PX 0 is rejected
+			}
+			args = append(args, []byte("PX"), []byte(strconv.FormatInt(px, 10)))
+		}
+		_, e = p.Do("SET", args...)
+		return int64(len(serialized)), e
+	}
+}
@@ -0,0 +1,173 @@
+package redis_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+	"github.com/takanoriyanagitani/go-simple-req-saver/redis"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+func testPoolNew(t *testing.T, s *testServer) *redis.Pool {
+	var p *redis.Pool = redis.PoolNew(func() (*redis.Conn, error) {
+		return redis.Dial("tcp", s.addr, time.Second)
+	}, 2)
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestConn(t *testing.T) {
+	t.Parallel()
+
+	var s *testServer = testServerNew(t)
+	var p *redis.Pool = testPoolNew(t, s)
+
+	t.Run("ping", func(t *testing.T) {
+		r, e := p.Do("PING")
+		t.Run("no error", assertNil(e))
+		t.Run("pong", assertEq(string(r.Str), "PONG"))
+	})
+
+	t.Run("nil bulk", func(t *testing.T) {
+		r, e := p.Do("GET", []byte("missing"))
+		t.Run("no error", assertNil(e))
+		t.Run("nil", assertTrue(r.Nil))
+	})
+
+	t.Run("error reply", func(t *testing.T) {
+		_, e := p.Do("NOSUCHCMD")
+		var replyErr redis.Error
+		t.Run("reply error", assertTrue(errors.As(e, &replyErr)))
+		r, e := p.Do("PING")
+		t.Run("connection reused", assertNil(e))
+		t.Run("pong", assertEq(string(r.Str), "PONG"))
+	})
+
+	t.Run("array", func(t *testing.T) {
+		_, e := p.Do("RPUSH", []byte("arr"), []byte("a\r\nb"))
+		t.Run("no push error", assertNil(e))
+		r, e := p.Do("LRANGE", []byte("arr"), []byte("0"), []byte("-1"))
+		t.Run("no range error", assertNil(e))
+		t.Run("1 item", assertEq(len(r.Array), 1))
+		t.Run("binary safe", assertEq(string(r.Array[0].Str), "a\r\nb"))
+	})
+
+	t.Run("pooled", func(t *testing.T) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("single connection", assertEq(s.conns, 1))
+	})
+}
+
+func TestSaver(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ListSaverNew", func(t *testing.T) {
+		t.Parallel()
+
+		var s *testServer = testServerNew(t)
+		var p *redis.Pool = testPoolNew(t, s)
+
+		var left saver.BytesSaver = redis.ListSaverNew(p, []byte("left"), redis.PushLeft, 2)
+		var right saver.BytesSaver = redis.ListSaverNew(p, []byte("right"), redis.PushRight, 2)
+		for _, item := range []string{"1", "2", "3"} {
+			_, e := left([]byte(item))
+			t.Run("no lpush error", assertNil(e))
+			_, e = right([]byte(item))
+			t.Run("no rpush error", assertNil(e))
+		}
+		t.Run("left capped", assertEq(strings.Join(s.list("left"), ","), "3,2"))
+		t.Run("right capped", assertEq(strings.Join(s.list("right"), ","), "2,3"))
+	})
+
+	t.Run("StreamSaverNew", func(t *testing.T) {
+		t.Parallel()
+
+		var s *testServer = testServerNew(t)
+		var p *redis.Pool = testPoolNew(t, s)
+
+		var sav saver.BytesSaver = redis.StreamSaverNew(p, []byte("captures"), []byte("req"), 1000)
+		cnt, e := sav([]byte("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("bytes count", assertEq(cnt, 2))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("xadd args", assertEq(
+			strings.Join(s.streams["captures"][0], " "),
+			"MAXLEN ~ 1000 * req hw",
+		))
+	})
+
+	t.Run("SetSaverNew", func(t *testing.T) {
+		t.Parallel()
+
+		var s *testServer = testServerNew(t)
+		var p *redis.Pool = testPoolNew(t, s)
+
+		var sav saver.BytesSaver = redis.SetSaverNew(p, redis.KeyRecordIDNew("req:"), time.Minute)
+		var x saver.ExchangeStd = saver.ExchangeStdNew(
+			httptest.NewRequest("POST", "/", nil),
+			[]byte("hw"),
+			"id-42",
+			time.Now(),
+		)
+		var rs saver.RequestSaver[saver.ExchangeStd, int64] = saver.RequestSaver[saver.ExchangeStd, int64](
+			sav.NewExchangeSaver(saver.ExchangeStd2bytesTar),
+		)
+		_, e := rs(x)
+		t.Run("no error", assertNil(e))
+
+		r, e := p.Do("GET", []byte("req:id-42"))
+		t.Run("no get error", assertNil(e))
+		parsed, e := saver.ExchangeStdFromTar(r.Str)
+		t.Run("no parse error", assertNil(e))
+		t.Run("body", assertEq(string(saver.Request[http.Header, []byte](parsed.Request).Body()), "hw"))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("ttl", assertEq(s.ttls["req:id-42"], "PX 60000"))
+	})
+
+	t.Run("SetSaverNew sub-millisecond ttl", func(t *testing.T) {
+		t.Parallel()
+
+		var s *testServer = testServerNew(t)
+		var p *redis.Pool = testPoolNew(t, s)
+
+		var keyOf func([]byte) ([]byte, error) = func(_ []byte) ([]byte, error) { return []byte("short"), nil }
+		var sav saver.BytesSaver = redis.SetSaverNew(p, keyOf, 500*time.Microsecond)
+		_, e := sav([]byte("hw"))
+		t.Run("no error", assertNil(e))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("ttl", assertEq(s.ttls["short"], "PX 1"))
+	})
+}
@@ -0,0 +1,164 @@
+package redis_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testServer is an in-process fake RESP2 server.
+type testServer struct {
+	lock    sync.Mutex
+	lists   map[string][]string
+	streams map[string][][]string
+	strings map[string]string
+	ttls    map[string]string
+	conns   int
+	addr    string
+}
+
+func testServerNew(t *testing.T) *testServer {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if nil != e {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	var s *testServer = &testServer{
+		lists:   make(map[string][]string),
+		streams: make(map[string][][]string),
+		strings: make(map[string]string),
+		ttls:    make(map[string]string),
+		addr:    l.Addr().String(),
+	}
+	go func() {
+		for {
+			conn, e := l.Accept()
+			if nil != e {
+				return
+			}
+			s.lock.Lock()
+			s.conns++
+			s.lock.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	var rdr *bufio.Reader = bufio.NewReader(conn)
+	for {
+		cmd, e := testReadCommand(rdr)
+		if nil != e {
+			return
+		}
+		_, e = io.WriteString(conn, s.exec(cmd))
+		if nil != e {
+			return
+		}
+	}
+}
+
+func testReadLine(rdr *bufio.Reader) (string, error) {
+	line, e := rdr.ReadString('\n')
+	return strings.TrimSuffix(line, "\r\n"), e
+}
+
+func testReadCommand(rdr *bufio.Reader) ([]string, error) {
+	line, e := testReadLine(rdr)
+	if nil != e {
+		return nil, e
+	}
+	cnt, e := strconv.Atoi(strings.TrimPrefix(line, "*"))
+	if nil != e {
+		return nil, e
+	}
+	var cmd []string
+	for i := 0; i < cnt; i++ {
+		line, e = testReadLine(rdr)
+		if nil != e {
+			return nil, e
+		}
+		size, e := strconv.Atoi(strings.TrimPrefix(line, "$"))
+		if nil != e {
+			return nil, e
+		}
+		var buf []byte = make([]byte, size+2)
+		_, e = io.ReadFull(rdr, buf)
+		if nil != e {
+			return nil, e
+		}
+		cmd = append(cmd, string(buf[:size]))
+	}
+	return cmd, nil
+}
+
+func (s *testServer) exec(cmd []string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch strings.ToUpper(cmd[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "LPUSH":
+		s.lists[cmd[1]] = append([]string{cmd[2]}, s.lists[cmd[1]]...)
+		return fmt.Sprintf(":%d\r\n", len(s.lists[cmd[1]]))
+	case "RPUSH":
+		s.lists[cmd[1]] = append(s.lists[cmd[1]], cmd[2])
+		return fmt.Sprintf(":%d\r\n", len(s.lists[cmd[1]]))
+	case "LTRIM":
+		var list []string = s.lists[cmd[1]]
+		start, _ := strconv.Atoi(cmd[2])
+		stop, _ := strconv.Atoi(cmd[3])
+		if start < 0 {
+			start += len(list)
+		}
+		if stop < 0 {
+			stop += len(list)
+		}
+		if start < 0 {
+			start = 0
+		}
+		if len(list) <= stop {
+			stop = len(list) - 1
+		}
+		s.lists[cmd[1]] = list[start : stop+1]
+		return "+OK\r\n"
+	case "XADD":
+		s.streams[cmd[1]] = append(s.streams[cmd[1]], cmd[2:])
+		return "$3\r\n1-0\r\n"
+	case "SET":
+		s.strings[cmd[1]] = cmd[2]
+		if 5 == len(cmd) {
+			s.ttls[cmd[1]] = cmd[3] + " " + cmd[4]
+		}
+		return "+OK\r\n"
+	case "GET":
+		val, found := s.strings[cmd[1]]
+		if !found {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(val), val)
+	case "LRANGE":
+		var list []string = s.lists[cmd[1]]
+		var b strings.Builder
+		_, _ = fmt.Fprintf(&b, "*%d\r\n", len(list))
+		for _, item := range list {
+			_, _ = fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(item), item)
+		}
+		return b.String()
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (s *testServer) list(key string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.lists[key]...)
+}
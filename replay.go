@@ -0,0 +1,309 @@
+package saver
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoRequestLine is returned when an exchange to replay has no method or uri.
+var ErrNoRequestLine error = errors.New("no method or uri")
+
+// ExchangeSource gets saved exchanges one by one; io.EOF means no more exchanges.
+type ExchangeSource func() (x ExchangeStd, e error)
+
+// Map creates a source which converts each exchange.
+func (s ExchangeSource) Map(f func(x ExchangeStd) (ExchangeStd, error)) ExchangeSource {
+	return func() (x ExchangeStd, e error) {
+		x, e = s()
+		if nil != e {
+			return x, e
+		}
+		return f(x)
+	}
+}
+
+// ExchangeRequestLineNew creates a func which fills a missing method and uri of an exchange.
+//
+// Records created by RequestSerializerNewGenericTar keep headers and a body only;
+// such records can be replayed using a known method and uri(sample: src.Map(ExchangeRequestLineNew("POST", "/api/v1/write"))).
+//
+// # Arguments
+//   - method: The method of records without a method.
+//   - uri: The uri(path?query) of records without a uri.
+func ExchangeRequestLineNew(method, uri string) func(x ExchangeStd) (ExchangeStd, error) {
+	return func(x ExchangeStd) (ExchangeStd, error) {
+		if "" == x.Method {
+			x.Method = method
+		}
+		if "" == x.URI {
+			x.URI = uri
+		}
+		return x, nil
+	}
+}
+
+// ExchangeSourceNewBlobs creates a source from serialized exchanges(tar blobs).
+func ExchangeSourceNewBlobs(blobs [][]byte) ExchangeSource {
+	var ix int
+	return func() (x ExchangeStd, e error) {
+		if len(blobs) <= ix {
+			return x, io.EOF
+		}
+		ix += 1
+		return ExchangeStdFromTar(blobs[ix-1])
+	}
+}
+
+// ExchangeSourceNewDir creates a source from files in a directory(in lexical order).
+//
+// Files can be created by RequestSaverNewFsSelfChecked and its variants.
+// Files without a method and a uri(RequestSerializerNewGenericTar) can not be replayed as is; see ExchangeRequestLineNew.
+func ExchangeSourceNewDir(dir string) (ExchangeSource, error) {
+	var names []string
+	e := filepath.WalkDir(dir, func(path string, d fs.DirEntry, e error) error {
+		if nil == e && d.Type().IsRegular() {
+			names = append(names, path)
+		}
+		return e
+	})
+	if nil != e {
+		return nil, e
+	}
+	var ix int
+	return func() (x ExchangeStd, e error) {
+		if len(names) <= ix {
+			return x, io.EOF
+		}
+		ix += 1
+		return Compose(os.ReadFile, func(serialized []byte) (ExchangeStd, error) {
+			return ExchangeStdFromTar(serialized)
+		})(names[ix-1])
+	}, nil
+}
+
+// ExchangeSourceNewStream creates a source from concatenated tar archives.
+//
+// A stream can be created by RequestSaverNewWriter.
+func ExchangeSourceNewStream(r io.Reader) ExchangeSource {
+	var br *bufio.Reader = bufio.NewReader(r)
+	return func() (x ExchangeStd, e error) {
+		_, e = br.Peek(1)
+		if nil != e {
+			return x, e
+		}
+		return ExchangeStdReadTar(tar.NewReader(br))
+	}
+}
+
+// ReplayResult is a result of a replayed exchange.
+type ReplayResult struct {
+	ID             string
+	Method         string
+	URI            string
+	RecordedStatus int // 0 if no response was saved
+	ReplayedStatus int // 0 if the request failed
+	Latency        time.Duration
+	Err            error
+}
+
+// Differs checks if the replayed request failed or got a different status.
+func (r ReplayResult) Differs() bool {
+	var statusDiffers bool = 0 != r.RecordedStatus && r.RecordedStatus != r.ReplayedStatus
+	return nil != r.Err || statusDiffers
+}
+
+// ReplayHeaderRewriteNew creates a header rewriter.
+//
+// # Arguments
+//   - set: Headers to overwrite(sample: Authorization of a staging environment).
+//   - del: Headers to remove.
+func ReplayHeaderRewriteNew(set map[string]string, del []string) func(h http.Header) {
+	return func(h http.Header) {
+		for _, key := range del {
+			h.Del(key)
+		}
+		for key, val := range set {
+			h.Set(key, val)
+		}
+	}
+}
+
+// Replayer re-sends saved exchanges to a target.
+type Replayer struct {
+	// Client sends requests.
+	Client *http.Client
+
+	// Target is the base url of the target(sample: https://staging.example.com/).
+	Target *url.URL
+
+	// Host overwrites the Host of requests(the host of the Target if empty).
+	Host string
+
+	// RewriteHeader rewrites headers of requests(nil: no rewrite).
+	RewriteHeader func(h http.Header)
+
+	// Speed multiplies the original timing; 0 sends requests without waiting.
+	Speed float64
+
+	// Concurrency is the max number of running requests(at least 1).
+	Concurrency int
+
+	// Blobs restores request bodies spilled by ExchangeStd2bytesSpillNew(nil: such exchanges fail).
+	Blobs BlobStore
+}
+
+// ReplayerNew creates a replayer which uses the default client.
+//
+// # Arguments
+//   - target: The base url of the target.
+//   - speed: 1 keeps the original timing, 2 replays twice as fast, 0 replays without waiting.
+//   - concurrency: Max number of running requests.
+func ReplayerNew(target *url.URL, speed float64, concurrency int) *Replayer {
+	return &Replayer{
+		Client:      http.DefaultClient,
+		Target:      target,
+		Speed:       speed,
+		Concurrency: concurrency,
+	}
+}
+
+// targetURL joins the base url of a target and a request uri(path?query).
+func targetURL(base *url.URL, uri string) (string, error) {
+	u, e := url.ParseRequestURI(uri)
+	if nil != e {
+		return "", e
+	}
+	var target url.URL = *base
+	target.Path = strings.TrimSuffix(target.Path, "/") + u.Path
+	target.RawPath = ""
+	target.RawQuery = u.RawQuery
+	return target.String(), nil
+}
+
+func (p *Replayer) request(ctx context.Context, x ExchangeStd) (*http.Request, error) {
+	if "" == x.Method || "" == x.URI {
+		return nil, fmt.Errorf("%w: record %q", ErrNoRequestLine, x.ID)
+	}
+	target, e := targetURL(p.Target, x.URI)
+	if nil != e {
+		return nil, e
+	}
+	x, e = ExchangeResolveNew(p.Blobs)(x)
+	if nil != e {
+		return nil, e
+	}
+	var original Request[http.Header, []byte] = Request[http.Header, []byte](x.Request)
+	q, e := http.NewRequestWithContext(ctx, x.Method, target, bytes.NewReader(original.Body()))
+	if nil != e {
+		return nil, e
+	}
+	q.Header = original.Header().Clone()
+	if nil == q.Header {
+		q.Header = make(http.Header)
+	}
+	q.Header.Del("Content-Length")
+	q.Header.Del("Host")
+	q.Host = p.Host
+	if nil != p.RewriteHeader {
+		p.RewriteHeader(q.Header)
+	}
+	return q, nil
+}
+
+func (p *Replayer) send(ctx context.Context, x ExchangeStd) (r ReplayResult) {
+	r.ID, r.Method, r.URI = x.ID, x.Method, x.URI
+	if nil != x.Response {
+		r.RecordedStatus = x.Response.Status
+	}
+	var started time.Time = time.Now()
+	q, e := p.request(ctx, x)
+	if nil != e {
+		r.Err = e
+		return
+	}
+	res, e := p.Client.Do(q)
+	r.Latency = time.Since(started)
+	if nil != e {
+		r.Err = e
+		return
+	}
+	_, _ = io.Copy(io.Discard, res.Body) // reuse the connection
+	r.ReplayedStatus = res.StatusCode
+	r.Err = res.Body.Close()
+	return
+}
+
+func (p *Replayer) wait(ctx context.Context, first, recorded, started time.Time) error {
+	if p.Speed <= 0 || first.IsZero() || recorded.IsZero() {
+		return nil
+	}
+	var offset time.Duration = time.Duration(float64(recorded.Sub(first)) / p.Speed)
+	var timer *time.Timer = time.NewTimer(time.Until(started.Add(offset)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Replay re-sends all exchanges from a source.
+//
+// # Arguments
+//   - ctx: Cancels the replay.
+//   - src: Gets saved exchanges(in the recorded order).
+//   - report: Gets a result of each exchange(may be called concurrently).
+func (p *Replayer) Replay(ctx context.Context, src ExchangeSource, report func(ReplayResult)) error {
+	var concurrency int = p.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var sem chan struct{} = make(chan struct{}, concurrency)
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	var started time.Time = time.Now()
+	var first time.Time
+	for {
+		x, e := src()
+		if errors.Is(e, io.EOF) {
+			return nil
+		}
+		if nil != e {
+			return e
+		}
+		if first.IsZero() {
+			first = x.Time
+		}
+		e = p.wait(ctx, first, x.Time, started)
+		if nil != e {
+			return e
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+		running.Add(1)
+		go func(x ExchangeStd) {
+			defer running.Done()
+			defer func() { <-sem }()
+			report(p.send(ctx, x))
+		}(x)
+	}
+}
@@ -0,0 +1,173 @@
+package saver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func testReplayExchange(id, uri string, status int, at time.Time) saver.ExchangeStd {
+	var q *http.Request = httptest.NewRequest("POST", uri, nil)
+	q.Header.Set("Authorization", "Bearer production")
+	var x saver.ExchangeStd = saver.ExchangeStdNew(q, []byte(id), id, at)
+	x.Response = &saver.ResponseStd{Status: status}
+	return x
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	var at time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+	var exchanges []saver.ExchangeStd = []saver.ExchangeStd{
+		testReplayExchange("1", "/hooks/a?x=1", 200, at),
+		testReplayExchange("2", "/hooks/b", 200, at.Add(100*time.Millisecond)),
+	}
+
+	var newTarget func(t *testing.T, auth *sync.Map) *url.URL = func(t *testing.T, auth *sync.Map) *url.URL {
+		var svr *httptest.Server = httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, q *http.Request) {
+				body, _ := io.ReadAll(q.Body)
+				auth.Store(string(body), q.Header.Get("Authorization")+" "+q.URL.String())
+				if "/base/hooks/b" == q.URL.Path {
+					w.WriteHeader(500)
+				}
+			},
+		))
+		t.Cleanup(svr.Close)
+		target, e := url.Parse(svr.URL + "/base/")
+		if nil != e {
+			t.Fatal(e)
+		}
+		return target
+	}
+
+	var replay func(t *testing.T, src saver.ExchangeSource) []saver.ReplayResult = func(
+		t *testing.T,
+		src saver.ExchangeSource,
+	) []saver.ReplayResult {
+		var auth sync.Map
+		var p *saver.Replayer = saver.ReplayerNew(newTarget(t, &auth), 10, 2)
+		p.RewriteHeader = saver.ReplayHeaderRewriteNew(
+			map[string]string{"Authorization": "Bearer staging"},
+			nil,
+		)
+
+		var lock sync.Mutex
+		var results []saver.ReplayResult
+		e := p.Replay(context.Background(), src, func(r saver.ReplayResult) {
+			lock.Lock()
+			defer lock.Unlock()
+			results = append(results, r)
+		})
+		t.Run("no replay error", assertNil(e))
+		sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+		seen, _ := auth.Load("1")
+		t.Run("rewritten", assertEq(seen, any("Bearer staging /base/hooks/a?x=1")))
+		return results
+	}
+
+	t.Run("blobs", func(t *testing.T) {
+		t.Parallel()
+
+		var blobs [][]byte
+		for _, x := range exchanges {
+			serialized, e := saver.ExchangeStd2bytesTar(x)
+			t.Run("no serialize error", assertNil(e))
+			blobs = append(blobs, serialized)
+		}
+
+		var results []saver.ReplayResult = replay(t, saver.ExchangeSourceNewBlobs(blobs))
+		t.Run("2 results", assertEq(len(results), 2))
+		t.Run("same status", assertEq(results[0].Differs(), false))
+		t.Run("different status", assertEq(results[1].Differs(), true))
+		t.Run("replayed status", assertEq(results[1].ReplayedStatus, 500))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		var rs saver.RequestSaver[saver.ExchangeStd, int64] = saver.RequestSaverNewWriter(
+			saver.ExchangeStd2bytesTar,
+			&buf,
+		)
+		for _, x := range exchanges {
+			_, e := rs(x)
+			t.Run("no save error", assertNil(e))
+		}
+
+		var results []saver.ReplayResult = replay(t, saver.ExchangeSourceNewStream(&buf))
+		t.Run("2 results", assertEq(len(results), 2))
+		t.Run("different status", assertEq(results[1].Differs(), true))
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string = t.TempDir()
+		for _, x := range exchanges {
+			var rs saver.RequestSaver[saver.ExchangeStd, int64] = saver.RequestSaverNewFsSelfCheckedWithFileMode(
+				saver.ExchangeStd2bytesTar,
+				func() (fullpath string) { return filepath.Join(dir, x.ID+".tar") },
+				0644,
+			)
+			_, e := rs(x)
+			t.Run("no save error", assertNil(e))
+		}
+
+		src, e := saver.ExchangeSourceNewDir(dir)
+		t.Run("no dir error", assertNil(e))
+		var results []saver.ReplayResult = replay(t, src)
+		t.Run("2 results", assertEq(len(results), 2))
+	})
+
+	t.Run("request only records", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string = t.TempDir()
+		var ts saver.RequestSerializer[
+			[]byte, http.Header, []byte,
+		] = saver.RequestSerializerNewGenericTar(
+			func(h http.Header, user func(key, val []byte)) {
+				for key, values := range h {
+					for _, val := range values {
+						user([]byte(key), []byte(val))
+					}
+				}
+			},
+			func(key []byte) string { return string(key) },
+			func(body []byte) []byte { return body },
+			func(e error) { panic(e) },
+		)
+		var rs saver.RequestSaver[saver.Request[http.Header, []byte], int64] = saver.RequestSaverNewFsSelfCheckedWithFileMode(
+			ts,
+			func() (fullpath string) { return filepath.Join(dir, "1.tar") },
+			0644,
+		)
+		_, e := rs(saver.RequestNew(http.Header{"Authorization": {"Bearer production"}}, []byte("1")))
+		t.Run("no save error", assertNil(e))
+
+		src, _ := saver.ExchangeSourceNewDir(dir)
+		var p *saver.Replayer = saver.ReplayerNew(newTarget(t, &sync.Map{}), 0, 1)
+		var results []saver.ReplayResult
+		_ = p.Replay(context.Background(), src, func(r saver.ReplayResult) { results = append(results, r) })
+		t.Run("no request line", assertTrue(errors.Is(results[0].Err, saver.ErrNoRequestLine)))
+
+		src, _ = saver.ExchangeSourceNewDir(dir)
+		var replayed []saver.ReplayResult = replay(t, src.Map(saver.ExchangeRequestLineNew("POST", "/hooks/a?x=1")))
+		t.Run("replayed", assertNil(replayed[0].Err))
+		t.Run("method", assertEq(replayed[0].Method, "POST"))
+	})
+}
@@ -0,0 +1,126 @@
+package saver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ResultWriter writes a save result; can be passed to ToHandlerFunc.
+type ResultWriter[R any] func(result R, e error, writer http.ResponseWriter)
+
+// ErrorRule maps matched errors to a http status code and an error code.
+type ErrorRule struct {
+	Match  func(e error) bool
+	Status int
+	Code   string
+}
+
+// ErrorRuleNew creates a rule which matches errors using errors.Is.
+func ErrorRuleNew(target error, status int, code string) ErrorRule {
+	return ErrorRule{
+		Match:  func(e error) bool { return errors.Is(e, target) },
+		Status: status,
+		Code:   code,
+	}
+}
+
+// ErrorRules are checked in order; the first matched rule is used.
+type ErrorRules []ErrorRule
+
+// ErrorRulesDefault maps errors of this package(used by PrecheckStatus and SaveFailureBlock).
+var ErrorRulesDefault ErrorRules = ErrorRules{
+	ErrorRuleNew(ErrBodyTooLarge, http.StatusRequestEntityTooLarge, "body_too_large"),
+	ErrorRuleNew(RequestLimiterErrTooMany, http.StatusTooManyRequests, "too_many_requests"),
+	ErrorRuleNew(ErrQuotaExceeded, http.StatusTooManyRequests, "quota_exceeded"),
+	ErrorRuleNew(ErrQueueFull, http.StatusServiceUnavailable, "queue_full"),
+	ErrorRuleNew(ErrQueueClosed, http.StatusServiceUnavailable, "queue_closed"),
+}
+
+// Lookup gets a status code and an error code for an error.
+//
+// # Returns
+//   - 200 and an empty code for nil.
+//   - The status/code of the first matched rule.
+//   - 500 and "internal" for unmatched errors.
+func (r ErrorRules) Lookup(e error) (status int, code string) {
+	return r.LookupOr(e, http.StatusInternalServerError, "internal")
+}
+
+// LookupOr gets a status code and an error code for an error; unmatched errors get the fallback.
+func (r ErrorRules) LookupOr(e error, fallbackStatus int, fallbackCode string) (status int, code string) {
+	if nil == e {
+		return http.StatusOK, ""
+	}
+	for _, rule := range r {
+		if rule.Match(e) {
+			return rule.Status, rule.Code
+		}
+	}
+	return fallbackStatus, fallbackCode
+}
+
+// ResultInfoBytesCount gets result info from a number of saved bytes(no record id).
+func ResultInfoBytesCount(bytesCount int64) (id string, count int64) { return "", bytesCount }
+
+// ResultWriterTextNew creates a writer which writes a plain text.
+//
+// A saved request gets 200 and "saved"; errors get a status code and an error code from rules.
+func ResultWriterTextNew[R any](rules ErrorRules) ResultWriter[R] {
+	return func(_ R, e error, w http.ResponseWriter) {
+		status, code := rules.Lookup(e)
+		if nil != e {
+			http.Error(w, code, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("saved\n"))
+	}
+}
+
+type resultJSON struct {
+	ID    string `json:"id,omitempty"`
+	Bytes int64  `json:"bytes"`
+	Error string `json:"error,omitempty"`
+}
+
+// ResultWriterJSONNew creates a writer which writes a JSON object.
+//
+// The object has a record id("id"), the number of saved bytes("bytes")
+// and an error code("error") for failed saves.
+//
+// # Arguments
+//   - rules: Maps errors to status/error codes.
+//   - info: Gets a record id and a number of bytes from a result.
+func ResultWriterJSONNew[R any](
+	rules ErrorRules,
+	info func(result R) (id string, bytesCount int64),
+) ResultWriter[R] {
+	return func(result R, e error, w http.ResponseWriter) {
+		status, code := rules.Lookup(e)
+		var body resultJSON = resultJSON{Error: code}
+		if nil == e {
+			body.ID, body.Bytes = info(result)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(&body) // headers already sent
+	}
+}
+
+// ResultWriterAcceptedNew creates a writer for async saves.
+//
+// An accepted(queued) request gets 202; errors get a status code and an error code from rules.
+func ResultWriterAcceptedNew[R any](rules ErrorRules) ResultWriter[R] {
+	return func(_ R, e error, w http.ResponseWriter) {
+		if nil != e {
+			status, code := rules.Lookup(e)
+			http.Error(w, code, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("accepted\n"))
+	}
+}
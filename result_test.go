@@ -0,0 +1,90 @@
+package saver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestResult(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ErrorRules", func(t *testing.T) {
+		t.Parallel()
+
+		status, code := saver.ErrorRulesDefault.Lookup(saver.ErrQuotaExceeded)
+		t.Run("quota status", assertEq(status, 429))
+		t.Run("quota code", assertEq(code, "quota_exceeded"))
+
+		status, code = saver.ErrorRulesDefault.Lookup(saver.ErrQueueFull)
+		t.Run("queue full status", assertEq(status, 503))
+		t.Run("queue full code", assertEq(code, "queue_full"))
+		status, _ = saver.ErrorRulesDefault.Lookup(saver.ErrQueueClosed)
+		t.Run("queue closed status", assertEq(status, 503))
+
+		status, code = saver.ErrorRulesDefault.Lookup(errors.New("redis down"))
+		t.Run("unknown status", assertEq(status, 500))
+		t.Run("unknown code", assertEq(code, "internal"))
+
+		status, code = saver.ErrorRulesDefault.LookupOr(errors.New("bad"), 400, "bad_request")
+		t.Run("fallback status", assertEq(status, 400))
+		t.Run("fallback code", assertEq(code, "bad_request"))
+		status, _ = saver.ErrorRulesDefault.LookupOr(saver.ErrBodyTooLarge, 400, "bad_request")
+		t.Run("matched before the fallback", assertEq(status, 413))
+	})
+
+	t.Run("ResultWriterTextNew", func(t *testing.T) {
+		t.Parallel()
+
+		var rw saver.ResultWriter[int64] = saver.ResultWriterTextNew[int64](saver.ErrorRulesDefault)
+		var w *httptest.ResponseRecorder = httptest.NewRecorder()
+		rw(0, saver.ErrBodyTooLarge, w)
+		t.Run("status", assertEq(w.Code, 413))
+		t.Run("content type", assertTrue(strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain")))
+	})
+
+	t.Run("ResultWriterJSONNew", func(t *testing.T) {
+		t.Parallel()
+
+		var rw saver.ResultWriter[int64] = saver.ResultWriterJSONNew(
+			saver.ErrorRulesDefault,
+			saver.ResultInfoBytesCount,
+		)
+
+		var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+			return int64(len(serialized)), nil
+		}
+		var hf http.HandlerFunc = sav.NewRequestSaverStd(saver.DupStdRequestSerializerNew()).ToHandlerFunc(rw)
+		var w *httptest.ResponseRecorder = httptest.NewRecorder()
+		hf(w, httptest.NewRequest("POST", "/", strings.NewReader("hw")))
+
+		t.Run("status", assertEq(w.Code, 200))
+		t.Run("content type", assertEq(w.Header().Get("Content-Type"), "application/json"))
+
+		var parsed map[string]any
+		e := json.Unmarshal(w.Body.Bytes(), &parsed)
+		t.Run("no parse error", assertNil(e))
+		t.Run("bytes", assertEq(parsed["bytes"], any(2.0)))
+		_, found := parsed["error"]
+		t.Run("no error code", assertEq(found, false))
+
+		w = httptest.NewRecorder()
+		rw(0, saver.RequestLimiterErrTooMany, w)
+		t.Run("error status", assertEq(w.Code, 429))
+		t.Run("error code", assertTrue(strings.Contains(w.Body.String(), `"too_many_requests"`)))
+	})
+
+	t.Run("ResultWriterAcceptedNew", func(t *testing.T) {
+		t.Parallel()
+
+		var rw saver.ResultWriter[int64] = saver.ResultWriterAcceptedNew[int64](saver.ErrorRulesDefault)
+		var w *httptest.ResponseRecorder = httptest.NewRecorder()
+		rw(0, nil, w)
+		t.Run("status", assertEq(w.Code, 202))
+	})
+}
@@ -0,0 +1,251 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPartSize is returned when a part size of a multipart upload is less than the MinPartSize.
+var ErrInvalidPartSize error = errors.New("invalid part size")
+
+// ResponseError is returned when a server responds with an unexpected status.
+type ResponseError struct {
+	Status int
+	Body   string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.Status, e.Body)
+}
+
+func retryable(status int) bool {
+	return http.StatusTooManyRequests == status || http.StatusInternalServerError <= status
+}
+
+// Client is a minimal client of S3 compatible storage(path-style urls).
+type Client struct {
+	// HTTP sends requests.
+	HTTP *http.Client
+
+	// Retries is the number of retries after a network error, 429 or 5xx.
+	Retries int
+
+	// Backoff is the first wait before a retry; doubled for each retry.
+	Backoff time.Duration
+
+	// MinPartSize is the min size of parts of a multipart upload except the last part(S3 requires 5 MiB).
+	MinPartSize int
+
+	// Timeout bounds an upload or a download of SaverNew and BlobStore including retries(0: no timeout).
+	Timeout time.Duration
+
+	endpoint *url.URL
+	bucket   string
+	signer   Signer
+	now      func() time.Time
+}
+
+// ClientNew creates a client which uses the default http client, retries 3 times and times out after 1 minute.
+//
+// Parts of multipart uploads must be 5 MiB or more(see MinPartSize).
+//
+// # Arguments
+//   - endpoint: The endpoint(sample: http://127.0.0.1:9000).
+//   - bucket: The bucket name.
+//   - signer: Signs requests(Service should be "s3").
+func ClientNew(endpoint *url.URL, bucket string, signer Signer) *Client {
+	return &Client{
+		HTTP:        http.DefaultClient,
+		Retries:     3,
+		Backoff:     100 * time.Millisecond,
+		Timeout:     time.Minute,
+		MinPartSize: 5 << 20,
+		endpoint:    endpoint,
+		bucket:      bucket,
+		signer:      signer,
+		now:         time.Now,
+	}
+}
+
+// context creates a context of an operation bounded by the Timeout.
+func (c *Client) context() (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), c.Timeout)
+}
+
+// partSize raises a part size to the MinPartSize.
+func (c *Client) partSize(size int) int {
+	if size < c.MinPartSize {
+		return c.MinPartSize
+	}
+	return size
+}
+
+func (c *Client) objectURL(key string, query url.Values) string {
+	var u url.URL = *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.bucket + "/" + key
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (c *Client) once(
+	ctx context.Context,
+	method, key string,
+	query url.Values,
+	body []byte,
+) (status int, header http.Header, resBody []byte, e error) {
+	q, e := http.NewRequestWithContext(ctx, method, c.objectURL(key, query), bytes.NewReader(body))
+	if nil != e {
+		return 0, nil, nil, e
+	}
+	c.signer.Sign(q, PayloadHash(body), c.now())
+	res, e := c.HTTP.Do(q)
+	if nil != e {
+		return 0, nil, nil, e
+	}
+	defer res.Body.Close()
+	resBody, e = io.ReadAll(res.Body)
+	return res.StatusCode, res.Header, resBody, e
+}
+
+// do sends a signed request and retries on a network error, 429 or 5xx.
+func (c *Client) do(
+	ctx context.Context,
+	method, key string,
+	query url.Values,
+	body []byte,
+) (http.Header, []byte, error) {
+	var wait time.Duration = c.Backoff
+	for attempt := 0; ; attempt++ {
+		status, header, resBody, e := c.once(ctx, method, key, query, body)
+		if nil == e && !retryable(status) {
+			if status < 200 || 300 <= status {
+				return header, resBody, &ResponseError{Status: status, Body: string(resBody)}
+			}
+			return header, resBody, nil
+		}
+		if nil == e {
+			e = &ResponseError{Status: status, Body: string(resBody)}
+		}
+		if c.Retries <= attempt {
+			return header, resBody, e
+		}
+		select {
+		case <-ctx.Done():
+			return header, resBody, errors.Join(e, ctx.Err())
+		case <-time.After(wait):
+			wait *= 2
+		}
+	}
+}
+
+// PutObject uploads an object.
+func (c *Client) PutObject(ctx context.Context, key string, body []byte) error {
+	_, _, e := c.do(ctx, http.MethodPut, key, nil, body)
+	return e
+}
+
+// GetObject downloads an object.
+func (c *Client) GetObject(ctx context.Context, key string) ([]byte, error) {
+	_, body, e := c.do(ctx, http.MethodGet, key, nil, nil)
+	if nil != e {
+		return nil, e
+	}
+	return body, nil
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// completeError checks a body of a completed upload; S3 may answer an error with 200.
+func completeError(body []byte) error {
+	var root struct{ XMLName xml.Name }
+	if nil == xml.Unmarshal(body, &root) && "Error" == root.XMLName.Local {
+		return &ResponseError{Status: http.StatusOK, Body: string(body)}
+	}
+	return nil
+}
+
+// PutObjectMultipart uploads an object using a multipart upload.
+//
+// The upload is aborted if a part can not be uploaded or the upload can not be completed
+// (including an error in a body of a 200 response).
+//
+// # Arguments
+//   - ctx: Cancels the upload.
+//   - key: The object key.
+//   - body: The object content.
+//   - partSize: The size of each part except the last part(ErrInvalidPartSize if less than the MinPartSize).
+func (c *Client) PutObjectMultipart(ctx context.Context, key string, body []byte, partSize int) error {
+	if partSize <= 0 || partSize < c.MinPartSize {
+		return fmt.Errorf("%w: %d(min %d)", ErrInvalidPartSize, partSize, c.MinPartSize)
+	}
+	_, initiated, e := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if nil != e {
+		return e
+	}
+	var result initiateMultipartUploadResult
+	e = xml.Unmarshal(initiated, &result)
+	if nil != e {
+		return e
+	}
+	var uploadID url.Values = url.Values{"uploadId": {result.UploadID}}
+
+	var complete completeMultipartUpload
+	for offset, number := 0, 1; offset < len(body); offset, number = offset+partSize, number+1 {
+		var end int = offset + partSize
+		if len(body) < end {
+			end = len(body)
+		}
+		var query url.Values = url.Values{
+			"uploadId":   {result.UploadID},
+			"partNumber": {strconv.Itoa(number)},
+		}
+		header, _, e := c.do(ctx, http.MethodPut, key, query, body[offset:end])
+		if nil != e {
+			_, _, abortErr := c.do(ctx, http.MethodDelete, key, uploadID, nil)
+			return errors.Join(e, abortErr)
+		}
+		complete.Parts = append(complete.Parts, completedPart{
+			PartNumber: number,
+			ETag:       header.Get("ETag"),
+		})
+	}
+
+	serialized, e := xml.Marshal(&complete)
+	if nil == e {
+		var completed []byte
+		_, completed, e = c.do(ctx, http.MethodPost, key, uploadID, serialized)
+		if nil == e {
+			e = completeError(completed)
+		}
+	}
+	if nil != e {
+		_, _, abortErr := c.do(ctx, http.MethodDelete, key, uploadID, nil)
+		return errors.Join(e, abortErr)
+	}
+	return nil
+}
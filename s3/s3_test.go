@@ -0,0 +1,324 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+	"github.com/takanoriyanagitani/go-simple-req-saver/s3"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+var testSigner s3.Signer = s3.Signer{
+	AccessKey: "AKIDEXAMPLE",
+	SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	Region:    "us-east-1",
+	Service:   "s3",
+}
+
+// testStorage is a fake S3 compatible server.
+type testStorage struct {
+	lock     sync.Mutex
+	objects  map[string][]byte
+	parts    map[string]map[string][]byte
+	failures int
+	requests int
+	badSigs  int
+
+	failComplete  bool
+	errorComplete bool // an error with 200
+	aborted       int
+}
+
+func (s *testStorage) verify(q *http.Request) bool {
+	signed, e := time.Parse("20060102T150405Z", q.Header.Get("X-Amz-Date"))
+	if nil != e {
+		return false
+	}
+	var cp *http.Request = q.Clone(q.Context())
+	cp.Header = http.Header{}
+	for _, key := range []string{"X-Amz-Date", "X-Amz-Content-Sha256"} {
+		cp.Header.Set(key, q.Header.Get(key))
+	}
+	testSigner.Sign(cp, q.Header.Get("X-Amz-Content-Sha256"), signed)
+	return cp.Header.Get("Authorization") == q.Header.Get("Authorization")
+}
+
+func (s *testStorage) ServeHTTP(w http.ResponseWriter, q *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests++
+	if 0 < s.failures {
+		s.failures--
+		w.WriteHeader(503)
+		return
+	}
+	body, _ := io.ReadAll(q.Body)
+	if !s.verify(q) || s3.PayloadHash(body) != q.Header.Get("X-Amz-Content-Sha256") {
+		s.badSigs++
+		w.WriteHeader(403)
+		return
+	}
+	var query url.Values = q.URL.Query()
+	var key string = q.URL.Path
+	switch {
+	case "POST" == q.Method && query.Has("uploads"):
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`))
+	case "PUT" == q.Method && query.Has("partNumber"):
+		if nil == s.parts[key] {
+			s.parts[key] = make(map[string][]byte)
+		}
+		s.parts[key][query.Get("partNumber")] = body
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+	case "POST" == q.Method && query.Has("uploadId") && s.failComplete:
+		w.WriteHeader(400)
+	case "POST" == q.Method && query.Has("uploadId") && s.errorComplete:
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<Error><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error>`))
+	case "DELETE" == q.Method && query.Has("uploadId"):
+		s.aborted++
+		w.WriteHeader(204)
+	case "POST" == q.Method && query.Has("uploadId"):
+		var complete struct {
+			Parts []struct {
+				PartNumber string `xml:"PartNumber"`
+				ETag       string `xml:"ETag"`
+			} `xml:"Part"`
+		}
+		_ = xml.Unmarshal(body, &complete)
+		var numbers []string
+		for _, part := range complete.Parts {
+			numbers = append(numbers, part.PartNumber)
+		}
+		sort.Strings(numbers)
+		var assembled []byte
+		for _, number := range numbers {
+			assembled = append(assembled, s.parts[key][number]...)
+		}
+		s.objects[key] = assembled
+	case "PUT" == q.Method:
+		s.objects[key] = body
+	case "GET" == q.Method:
+		object, found := s.objects[key]
+		if !found {
+			w.WriteHeader(404)
+			return
+		}
+		_, _ = w.Write(object)
+	default:
+		w.WriteHeader(400)
+	}
+}
+
+func testStorageNew(t *testing.T, failures int) (*testStorage, *s3.Client) {
+	var s *testStorage = &testStorage{
+		objects:  make(map[string][]byte),
+		parts:    make(map[string]map[string][]byte),
+		failures: failures,
+	}
+	var svr *httptest.Server = httptest.NewServer(s)
+	t.Cleanup(svr.Close)
+	endpoint, e := url.Parse(svr.URL)
+	if nil != e {
+		t.Fatal(e)
+	}
+	var c *s3.Client = s3.ClientNew(endpoint, "captures", testSigner)
+	c.Backoff = time.Millisecond
+	c.MinPartSize = 1
+	return s, c
+}
+
+func TestSigner(t *testing.T) {
+	t.Parallel()
+
+	// get-vanilla of the AWS SigV4 test suite
+	q, e := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	t.Run("no request error", assertNil(e))
+	var signer s3.Signer = testSigner
+	signer.Service = "service"
+	signer.Sign(q, s3.PayloadHash(nil), time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	t.Run("authorization", assertEq(
+		q.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, "+
+			"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+	))
+}
+
+func TestSaver(t *testing.T) {
+	t.Parallel()
+
+	var now func() time.Time = func() time.Time { return time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC) }
+
+	var keyOf func(template string) s3.KeyFunc = func(template string) s3.KeyFunc {
+		tmpl, e := saver.KeyTemplateNew(template, now)
+		if nil != e {
+			panic(e)
+		}
+		return tmpl.ForSerialized()
+	}
+
+	t.Run("key template", func(t *testing.T) {
+		t.Parallel()
+
+		var x saver.ExchangeStd = saver.ExchangeStdNew(httptest.NewRequest("POST", "/", nil), nil, "id-1", now())
+		serialized, _ := saver.ExchangeStd2bytesTar(x)
+		key, e := keyOf("cap/{time:2006/01/02}/{method}-{id}.tar")(serialized)
+		t.Run("no key error", assertNil(e))
+		t.Run("key", assertEq(key, "cap/2026/10/17/POST-id-1.tar"))
+	})
+
+	t.Run("put with retries", func(t *testing.T) {
+		t.Parallel()
+
+		s, c := testStorageNew(t, 2)
+		var sav saver.BytesSaver = s3.SaverNew(c, keyOf("a b/{sha256}"), 0)
+		cnt, e := sav([]byte("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("bytes count", assertEq(cnt, 2))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("retried", assertEq(s.requests, 3))
+		t.Run("signed", assertEq(s.badSigs, 0))
+		t.Run("stored", assertEq(string(s.objects["/captures/a b/"+s3.PayloadHash(nil)]), "hw"))
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		t.Parallel()
+
+		_, c := testStorageNew(t, 100)
+		c.Retries = 1
+		_, e := s3.SaverNew(c, keyOf("x"), 0)([]byte("hw"))
+		var re *s3.ResponseError
+		t.Run("response error", assertTrue(errors.As(e, &re)))
+		t.Run("status", assertEq(re.Status, 503))
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		t.Parallel()
+
+		s, c := testStorageNew(t, 0)
+		var blob []byte = bytes.Repeat([]byte("0123456789"), 10)
+		_, e := s3.SaverNew(c, keyOf("big"), 32)(blob)
+		t.Run("no error", assertNil(e))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("parts", assertEq(len(s.parts["/captures/big"]), 4))
+		t.Run("assembled", assertTrue(bytes.Equal(s.objects["/captures/big"], blob)))
+		t.Run("signed", assertEq(s.badSigs, 0))
+	})
+
+	t.Run("part size raised", func(t *testing.T) {
+		t.Parallel()
+
+		s, c := testStorageNew(t, 0)
+		c.MinPartSize = 64
+		var blob []byte = bytes.Repeat([]byte("0123456789"), 10)
+		_, e := s3.SaverNew(c, keyOf("big"), 32)(blob)
+		t.Run("no error", assertNil(e))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("parts", assertEq(len(s.parts["/captures/big"]), 2))
+		t.Run("assembled", assertTrue(bytes.Equal(s.objects["/captures/big"], blob)))
+	})
+
+	t.Run("BlobStoreNew", func(t *testing.T) {
+		t.Parallel()
+
+		s, c := testStorageNew(t, 0)
+		var blobs *s3.BlobStore = s3.BlobStoreNew(c, "blobs/", 0)
+		var body []byte = bytes.Repeat([]byte("0123456789"), 10)
+		var q *http.Request = httptest.NewRequest("PUT", "/upload", bytes.NewReader(body))
+		var x saver.ExchangeStd = saver.ExchangeStdNew(q, body, "id-1", now())
+
+		serialized, e := saver.ExchangeStd2bytesSpillNew(blobs, 32, saver.ExchangeStd2bytesTar)(x)
+		t.Run("no spill error", assertNil(e))
+		s.lock.Lock()
+		t.Run("uploaded", assertTrue(bytes.Equal(s.objects["/captures/blobs/"+s3.PayloadHash(body)], body)))
+		s.lock.Unlock()
+
+		resolved, e := saver.ExchangeSourceNewBlobs([][]byte{serialized}).ResolveBodies(blobs)()
+		t.Run("no resolve error", assertNil(e))
+		t.Run("body", assertTrue(bytes.Equal(saver.Request[http.Header, []byte](resolved.Request).Body(), body)))
+
+		_, e = blobs.GetBlob("missing")
+		var re *s3.ResponseError
+		t.Run("missing", assertTrue(errors.As(e, &re) && 404 == re.Status))
+	})
+
+	t.Run("multipart arguments", func(t *testing.T) {
+		t.Parallel()
+
+		s, c := testStorageNew(t, 0)
+		e := c.PutObjectMultipart(context.Background(), "big", []byte("hw"), 0)
+		t.Run("invalid part size", assertTrue(errors.Is(e, s3.ErrInvalidPartSize)))
+
+		s.lock.Lock()
+		s.failComplete = true
+		s.lock.Unlock()
+		e = c.PutObjectMultipart(context.Background(), "big", []byte("hw"), 1)
+		t.Run("complete error", assertTrue(nil != e))
+
+		s.lock.Lock()
+		s.failComplete = false
+		s.errorComplete = true
+		s.lock.Unlock()
+		e = c.PutObjectMultipart(context.Background(), "big", []byte("hw"), 1)
+		var re *s3.ResponseError
+		t.Run("error with 200", assertTrue(errors.As(e, &re) && strings.Contains(re.Body, "InternalError")))
+
+		c.MinPartSize = 5 << 20
+		e = c.PutObjectMultipart(context.Background(), "big", []byte("hw"), 1<<20)
+		t.Run("part too small", assertTrue(errors.Is(e, s3.ErrInvalidPartSize)))
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		t.Run("aborted", assertEq(s.aborted, 2))
+		t.Run("not assembled", assertEq(len(s.objects["/captures/big"]), 0))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		_, c := testStorageNew(t, 100)
+		c.Backoff = time.Hour
+		c.Timeout = 10 * time.Millisecond
+		_, e := s3.SaverNew(c, keyOf("x"), 0)([]byte("hw"))
+		t.Run("deadline", assertTrue(errors.Is(e, context.DeadlineExceeded)))
+	})
+}
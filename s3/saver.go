@@ -0,0 +1,67 @@
+package s3
+
+import (
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// KeyFunc gets an object key of a serialized request(sample: saver.KeyTemplate.ForSerialized).
+type KeyFunc func(serialized []byte) (key string, e error)
+
+// SaverNew creates a saver which uploads each serialized request as an object(bounded by the Timeout of the client).
+//
+// # Arguments
+//   - c: Uploads objects.
+//   - keyOf: Gets an object key(sample: a saver.KeyTemplate "captures/{time:2006/01/02}/{id}.tar").
+//   - partSize: Larger blobs are uploaded using a multipart upload(0: never; raised to the MinPartSize).
+func SaverNew(c *Client, keyOf KeyFunc, partSize int) saver.BytesSaver {
+	return func(serialized []byte) (bytesCount int64, e error) {
+		key, e := keyOf(serialized)
+		if nil != e {
+			return 0, e
+		}
+		ctx, cancel := c.context()
+		defer cancel()
+		switch {
+		case 0 < partSize && c.partSize(partSize) < len(serialized):
+			e = c.PutObjectMultipart(ctx, key, serialized, c.partSize(partSize))
+		default:
+			e = c.PutObject(ctx, key, serialized)
+		}
+		return int64(len(serialized)), e
+	}
+}
+
+// BlobStore is a saver.BlobStore which keeps a blob as an object: <prefix><sha256>.
+type BlobStore struct {
+	c        *Client
+	prefix   string
+	partSize int
+}
+
+// BlobStoreNew creates a blob store(sample: ExchangeStd2bytesSpillNew).
+//
+// # Arguments
+//   - c: Uploads and downloads objects.
+//   - prefix: A prefix of object keys(sample: "blobs/").
+//   - partSize: Larger blobs are uploaded using a multipart upload(0: never; raised to the MinPartSize).
+func BlobStoreNew(c *Client, prefix string, partSize int) *BlobStore {
+	return &BlobStore{c: c, prefix: prefix, partSize: partSize}
+}
+
+// PutBlob uploads a blob.
+func (s *BlobStore) PutBlob(digest string, blob []byte) error {
+	ctx, cancel := s.c.context()
+	defer cancel()
+	var key string = s.prefix + digest
+	if 0 < s.partSize && s.c.partSize(s.partSize) < len(blob) {
+		return s.c.PutObjectMultipart(ctx, key, blob, s.c.partSize(s.partSize))
+	}
+	return s.c.PutObject(ctx, key, blob)
+}
+
+// GetBlob downloads a blob.
+func (s *BlobStore) GetBlob(digest string) ([]byte, error) {
+	ctx, cancel := s.c.context()
+	defer cancel()
+	return s.c.GetObject(ctx, s.prefix+digest)
+}
@@ -0,0 +1,154 @@
+// Package s3 provides a BytesSaver which uploads serialized requests to S3 compatible storage.
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigv4Algorithm = "AWS4-HMAC-SHA256"
+	sigv4Time      = "20060102T150405Z"
+	sigv4Date      = "20060102"
+)
+
+// UnsignedPayload can be used as a payload hash to skip hashing a body.
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Signer signs requests using AWS Signature Version 4.
+type Signer struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+}
+
+// PayloadHash gets the hex encoded SHA-256 of a payload.
+func PayloadHash(payload []byte) string {
+	var digest [sha256.Size]byte = sha256.Sum256(payload)
+	return hex.EncodeToString(digest[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	var mac = hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data)) // always nil error
+	return mac.Sum(nil)
+}
+
+// uriEncode encodes a string as described in the SigV4 spec.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		var c byte = s[i]
+		var unreserved bool = 'A' <= c && c <= 'Z' ||
+			'a' <= c && c <= 'z' ||
+			'0' <= c && c <= '9' ||
+			'-' == c || '_' == c || '.' == c || '~' == c
+		switch {
+		case unreserved, '/' == c && !encodeSlash:
+			_ = b.WriteByte(c) // always nil error
+		default:
+			_, _ = b.WriteString("%")
+			_, _ = b.WriteString(strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	var pairs []string = make([]string, 0, len(query))
+	for key, values := range query {
+		for _, val := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(val, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func signedHeaderNames(h http.Header) []string {
+	var names []string = []string{"host"}
+	for key := range h {
+		var lower string = strings.ToLower(key)
+		var signed bool = strings.HasPrefix(lower, "x-amz-") ||
+			"content-type" == lower ||
+			"content-md5" == lower
+		if signed {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sign sets X-Amz-Date, X-Amz-Content-Sha256 and Authorization.
+//
+// # Arguments
+//   - q: A request to sign(the Host is taken from q.Host or q.URL.Host).
+//   - payloadHash: PayloadHash of the body or UnsignedPayload.
+//   - now: The signing time.
+func (s Signer) Sign(q *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	var amzTime string = now.Format(sigv4Time)
+	var date string = now.Format(sigv4Date)
+	q.Header.Set("X-Amz-Date", amzTime)
+	if "s3" == s.Service {
+		q.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	var host string = q.Host
+	if "" == host {
+		host = q.URL.Host
+	}
+	var names []string = signedHeaderNames(q.Header)
+	var headers strings.Builder
+	for _, name := range names {
+		var val string = host
+		if "host" != name {
+			val = strings.Join(q.Header.Values(name), ",")
+		}
+		_, _ = headers.WriteString(name + ":" + strings.TrimSpace(val) + "\n")
+	}
+	var signedHeaders string = strings.Join(names, ";")
+
+	var path string = q.URL.EscapedPath()
+	if unescaped, e := url.PathUnescape(path); nil == e {
+		path = unescaped
+	}
+	if "" == path {
+		path = "/"
+	}
+
+	var canonical string = strings.Join([]string{
+		q.Method,
+		uriEncode(path, false),
+		canonicalQuery(q.URL.Query()),
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	var scope string = strings.Join([]string{date, s.Region, s.Service, "aws4_request"}, "/")
+	var stringToSign string = strings.Join([]string{
+		sigv4Algorithm,
+		amzTime,
+		scope,
+		PayloadHash([]byte(canonical)),
+	}, "\n")
+
+	var key []byte = hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	var signature string = hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	q.Header.Set("Authorization", sigv4Algorithm+
+		" Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
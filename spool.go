@@ -0,0 +1,239 @@
+package saver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrClaimLost is returned when a claimed record was requeued or acked by another consumer.
+var ErrClaimLost error = errors.New("claim lost")
+
+const (
+	spoolTmp = "tmp"
+	spoolNew = "new"
+	spoolCur = "cur"
+
+	// spoolClaimSep separates a record name and its claim time(unix nanoseconds) in cur.
+	spoolClaimSep = ","
+)
+
+// Spool is a Maildir-style spool directory which can be drained by several processes.
+//
+//   - tmp: Records being written.
+//   - new: Records waiting for a consumer.
+//   - cur: Claimed records(the name contains the claim time).
+//
+// Every state change is a rename, which is atomic within a file system.
+type Spool struct {
+	dir   string
+	idGen RecordIDGen
+	now   func() time.Time
+	dirs  map[string]string
+}
+
+// SpoolNew creates a spool and its directories.
+//
+// # Arguments
+//   - dir: The spool directory.
+//   - now: Gets the current time(sample: time.Now); used for record names and claim times.
+func SpoolNew(dir string, now func() time.Time) (*Spool, error) {
+	var s *Spool = &Spool{
+		dir:   dir,
+		idGen: RecordIDGenRandom,
+		now:   now,
+		dirs:  make(map[string]string),
+	}
+	for _, name := range []string{spoolTmp, spoolNew, spoolCur} {
+		s.dirs[name] = filepath.Join(dir, name)
+		var e error = os.MkdirAll(s.dirs[name], 0755)
+		if nil != e {
+			return nil, e
+		}
+	}
+	return s, nil
+}
+
+// uniqueName creates a name which is ordered by the creation time.
+func (s *Spool) uniqueName() string {
+	return fmt.Sprintf("%019d.%s", s.now().UnixNano(), s.idGen())
+}
+
+// Save writes a serialized request to tmp(with fsync) and moves it to new.
+func (s *Spool) Save(serialized []byte) (bytesCount int64, e error) {
+	var name string = s.uniqueName()
+	var tmp string = filepath.Join(s.dirs[spoolTmp], name)
+	f, e := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if nil != e {
+		return 0, e
+	}
+	written, e := f.Write(serialized)
+	if nil == e {
+		e = f.Sync()
+	}
+	e = errors.Join(e, f.Close())
+	if nil != e {
+		return 0, errors.Join(e, os.Remove(tmp))
+	}
+	return int64(written), os.Rename(tmp, filepath.Join(s.dirs[spoolNew], name))
+}
+
+// SpoolRecord is a claimed record.
+type SpoolRecord struct {
+	// Name is the unique name of the record.
+	Name string
+
+	// Claimed is the claim time.
+	Claimed time.Time
+
+	// Data is the serialized request.
+	Data []byte
+
+	path string
+}
+
+// sortedNames gets names of entries of a spool directory in order.
+func (s *Spool) sortedNames(dir string) ([]string, error) {
+	entries, e := os.ReadDir(s.dirs[dir])
+	if nil != e {
+		return nil, e
+	}
+	var names []string = make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Claim moves up to n records from new to cur and reads them(oldest first).
+//
+// Records claimed concurrently by another consumer are skipped.
+func (s *Spool) Claim(n int) ([]SpoolRecord, error) {
+	names, e := s.sortedNames(spoolNew)
+	if nil != e {
+		return nil, e
+	}
+	var claimed []SpoolRecord
+	for _, name := range names {
+		if n <= len(claimed) {
+			break
+		}
+		var now time.Time = s.now()
+		var path string = filepath.Join(s.dirs[spoolCur], name+spoolClaimSep+strconv.FormatInt(now.UnixNano(), 10))
+		e = os.Rename(filepath.Join(s.dirs[spoolNew], name), path)
+		if errors.Is(e, fs.ErrNotExist) {
+			continue // claimed by another consumer
+		}
+		if nil != e {
+			return claimed, e
+		}
+		data, e := os.ReadFile(path)
+		if nil != e {
+			return claimed, e
+		}
+		claimed = append(claimed, SpoolRecord{Name: name, Claimed: now, Data: data, path: path})
+	}
+	return claimed, nil
+}
+
+// Ack removes a claimed record.
+func (s *Spool) Ack(r SpoolRecord) error {
+	var e error = os.Remove(r.path)
+	if errors.Is(e, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrClaimLost, r.Name)
+	}
+	return e
+}
+
+// Release moves a claimed record back to new.
+func (s *Spool) Release(r SpoolRecord) error {
+	var e error = os.Rename(r.path, filepath.Join(s.dirs[spoolNew], r.Name))
+	if errors.Is(e, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrClaimLost, r.Name)
+	}
+	return e
+}
+
+// RequeueStale moves records claimed before the timeout back to new.
+//
+// # Arguments
+//   - timeout: Claims older than this are treated as abandoned(a consumer crashed).
+func (s *Spool) RequeueStale(timeout time.Duration) (requeued int, e error) {
+	names, e := s.sortedNames(spoolCur)
+	if nil != e {
+		return 0, e
+	}
+	var now time.Time = s.now()
+	for _, claimedName := range names {
+		name, claimedAt, found := strings.Cut(claimedName, spoolClaimSep)
+		nanos, parseErr := strconv.ParseInt(claimedAt, 10, 64)
+		if !found || nil != parseErr {
+			continue // not a claimed record
+		}
+		if now.Sub(time.Unix(0, nanos)) < timeout {
+			continue
+		}
+		e = os.Rename(filepath.Join(s.dirs[spoolCur], claimedName), filepath.Join(s.dirs[spoolNew], name))
+		if errors.Is(e, fs.ErrNotExist) {
+			continue // acked or requeued by another consumer
+		}
+		if nil != e {
+			return requeued, e
+		}
+		requeued++
+	}
+	return requeued, nil
+}
+
+// SpoolConsumer is a Consumer of a spool; consumers of a spool share a single group.
+//
+// A Pull requeues claims older than its visibility timeout(RequeueStale) before claiming records;
+// a visibility timeout of 0 or less requeues nothing.
+// Deliveries are not counted by a spool: Attempts is always 1.
+type SpoolConsumer struct {
+	spool *Spool
+}
+
+// SpoolConsumerNew creates a consumer of a spool.
+func SpoolConsumerNew(s *Spool) *SpoolConsumer { return &SpoolConsumer{spool: s} }
+
+// Pull claims up to n records(oldest first).
+func (c *SpoolConsumer) Pull(n int, visibility time.Duration) ([]Delivery, error) {
+	if 0 < visibility {
+		_, e := c.spool.RequeueStale(visibility)
+		if nil != e {
+			return nil, e
+		}
+	}
+	claimed, e := c.spool.Claim(n)
+	var deliveries []Delivery = make([]Delivery, 0, len(claimed))
+	for _, r := range claimed {
+		deliveries = append(deliveries, Delivery{
+			Name:     r.Name,
+			Data:     r.Data,
+			Attempts: 1,
+			Deadline: r.Claimed.Add(visibility),
+			token:    r.path,
+		})
+	}
+	return deliveries, e
+}
+
+func (c *SpoolConsumer) record(d Delivery) SpoolRecord {
+	return SpoolRecord{Name: d.Name, Data: d.Data, path: d.token}
+}
+
+// Ack removes a claimed record(ErrClaimLost if it was requeued).
+func (c *SpoolConsumer) Ack(d Delivery) error { return c.spool.Ack(c.record(d)) }
+
+// Nack moves a claimed record back to new(ErrClaimLost if it was requeued).
+func (c *SpoolConsumer) Nack(d Delivery) error { return c.spool.Release(c.record(d)) }
@@ -0,0 +1,162 @@
+package saver_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func TestSpool(t *testing.T) {
+	t.Parallel()
+
+	var spoolNew func(t *testing.T, now func() time.Time) (string, *saver.Spool) = func(
+		t *testing.T,
+		now func() time.Time,
+	) (string, *saver.Spool) {
+		var dir string = t.TempDir()
+		s, e := saver.SpoolNew(dir, now)
+		if nil != e {
+			t.Fatal(e)
+		}
+		return dir, s
+	}
+
+	var count func(dir string) int = func(dir string) int {
+		entries, _ := os.ReadDir(dir)
+		return len(entries)
+	}
+
+	t.Run("claim and ack", func(t *testing.T) {
+		t.Parallel()
+
+		var now time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		dir, s := spoolNew(t, func() time.Time { return now })
+		var sav saver.BytesSaver = s.Save
+		for _, data := range []string{"first", "second", "third"} {
+			now = now.Add(time.Millisecond)
+			cnt, e := sav([]byte(data))
+			t.Run("no save error", assertNil(e))
+			t.Run("bytes count", assertEq(cnt, int64(len(data))))
+		}
+		t.Run("tmp empty", assertEq(count(filepath.Join(dir, "tmp")), 0))
+		t.Run("new", assertEq(count(filepath.Join(dir, "new")), 3))
+
+		claimed, e := s.Claim(2)
+		t.Run("no claim error", assertNil(e))
+		t.Run("claimed", assertEq(len(claimed), 2))
+		t.Run("oldest first", assertEq(string(claimed[0].Data), "first"))
+		t.Run("in order", assertEq(string(claimed[1].Data), "second"))
+		t.Run("cur", assertEq(count(filepath.Join(dir, "cur")), 2))
+
+		t.Run("no ack error", assertNil(s.Ack(claimed[0])))
+		t.Run("acked twice", assertTrue(errors.Is(s.Ack(claimed[0]), saver.ErrClaimLost)))
+		t.Run("no release error", assertNil(s.Release(claimed[1])))
+
+		claimed, _ = s.Claim(10)
+		t.Run("released first", assertEq(string(claimed[0].Data), "second"))
+		t.Run("rest", assertEq(len(claimed), 2))
+	})
+
+	t.Run("requeue stale", func(t *testing.T) {
+		t.Parallel()
+
+		var now time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		dir, s := spoolNew(t, func() time.Time { return now })
+		_, _ = s.Save([]byte("hw"))
+		claimed, _ := s.Claim(1)
+
+		requeued, e := s.RequeueStale(time.Hour)
+		t.Run("no error", assertNil(e))
+		t.Run("fresh claims kept", assertEq(requeued, 0))
+
+		now = now.Add(time.Hour)
+		requeued, _ = s.RequeueStale(time.Hour)
+		t.Run("stale claims requeued", assertEq(requeued, 1))
+		t.Run("new", assertEq(count(filepath.Join(dir, "new")), 1))
+		t.Run("claim lost", assertTrue(errors.Is(s.Ack(claimed[0]), saver.ErrClaimLost)))
+
+		reclaimed, _ := s.Claim(1)
+		t.Run("reclaimed", assertEq(string(reclaimed[0].Data), "hw"))
+		t.Run("old claim can not release", assertTrue(errors.Is(s.Release(claimed[0]), saver.ErrClaimLost)))
+		t.Run("no ack error", assertNil(s.Ack(reclaimed[0])))
+	})
+
+	t.Run("SpoolConsumer", func(t *testing.T) {
+		t.Parallel()
+
+		var now time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+		_, s := spoolNew(t, func() time.Time { return now })
+		for _, data := range []string{"a", "b"} {
+			now = now.Add(time.Millisecond)
+			_, _ = s.Save([]byte(data))
+		}
+		var c saver.Consumer = saver.SpoolConsumerNew(s)
+
+		pulled, e := c.Pull(1, time.Minute)
+		t.Run("no pull error", assertNil(e))
+		t.Run("oldest first", assertEq(string(pulled[0].Data), "a"))
+		t.Run("deadline", assertTrue(pulled[0].Deadline.Equal(now.Add(time.Minute))))
+
+		now = now.Add(2 * time.Minute)
+		redelivered, _ := c.Pull(1, time.Minute)
+		t.Run("requeued after the visibility timeout", assertEq(string(redelivered[0].Data), "a"))
+		t.Run("stale ack", assertTrue(errors.Is(c.Ack(pulled[0]), saver.ErrClaimLost)))
+		t.Run("no nack error", assertNil(c.Nack(redelivered[0])))
+
+		rest, _ := c.Pull(10, time.Minute)
+		t.Run("rest", assertEq(len(rest), 2))
+		for _, d := range rest {
+			t.Run("no ack error", assertNil(c.Ack(d)))
+		}
+		none, _ := c.Pull(10, time.Minute)
+		t.Run("all acked", assertEq(len(none), 0))
+	})
+
+	t.Run("concurrent consumers", func(t *testing.T) {
+		t.Parallel()
+
+		dir, s := spoolNew(t, time.Now)
+		for i := 0; i < 50; i++ {
+			_, _ = s.Save([]byte{byte(i)})
+		}
+
+		var lock sync.Mutex
+		var seen map[byte]int = make(map[byte]int)
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// a consumer process
+				s, e := saver.SpoolNew(dir, time.Now)
+				if nil != e {
+					return
+				}
+				for {
+					claimed, e := s.Claim(3)
+					if nil != e || 0 == len(claimed) {
+						return
+					}
+					for _, r := range claimed {
+						lock.Lock()
+						seen[r.Data[0]]++
+						lock.Unlock()
+						_ = s.Ack(r)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		var once bool = 50 == len(seen)
+		for _, n := range seen {
+			once = once && 1 == n
+		}
+		t.Run("each record once", assertTrue(once))
+	})
+}
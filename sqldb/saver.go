@@ -0,0 +1,291 @@
+package sqldb
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// Record is a row of a request table.
+type Record struct {
+	ID         string
+	ReceivedAt time.Time
+	Method     string
+	Path       string // the request uri(path?query)
+	Headers    string // JSON object of header values
+	Body       []byte
+	Checksum   string // hex encoded SHA-256 of the body
+}
+
+// RecordNew creates a record from an exchange.
+//
+// A random id and the current time are used for an exchange without an id or a time
+// (records of RequestSerializerNewGenericTar or RequestStd2bytesSpillNew).
+// A spilled request body(ExchangeStd2bytesSpillNew) must be resolved before(saver.ErrBodyUnresolved).
+func RecordNew(x saver.ExchangeStd) (Record, error) {
+	if nil != x.BodyRef {
+		return Record{}, fmt.Errorf("%w: %s", saver.ErrBodyUnresolved, x.BodyRef.Digest)
+	}
+	var q saver.Request[http.Header, []byte] = saver.Request[http.Header, []byte](x.Request)
+	var header http.Header = q.Header()
+	if nil == header {
+		header = http.Header{}
+	}
+	headers, e := json.Marshal(header)
+	if nil != e {
+		return Record{}, e
+	}
+	var digest [sha256.Size]byte = sha256.Sum256(q.Body())
+	var id string = x.ID
+	if "" == id {
+		id = saver.RecordIDGenRandom()
+	}
+	var received time.Time = x.Time
+	if received.IsZero() {
+		received = time.Now()
+	}
+	return Record{
+		ID:         id,
+		ReceivedAt: received.UTC(),
+		Method:     x.Method,
+		Path:       x.URI,
+		Headers:    string(headers),
+		Body:       q.Body(),
+		Checksum:   hex.EncodeToString(digest[:]),
+	}, nil
+}
+
+func (r Record) args() []any {
+	return []any{r.ID, r.ReceivedAt, r.Method, r.Path, r.Headers, r.Body, r.Checksum}
+}
+
+// pendingRecord is a buffered record.
+type pendingRecord struct {
+	Record
+	seq      uint64
+	attempts int
+}
+
+// Saver inserts records in batches using prepared statements.
+//
+// Records are buffered until a batch is full; Flush(or FlushEvery) must be called to insert the rest.
+//
+// When a batch insert fails, its records are inserted one by one.
+// Records which can not be inserted are kept for the next insert;
+// a record which failed MaxAttempts inserts is passed to OnDeadLetter and is dropped.
+// Attempts are not counted when no record could be inserted(the database is unavailable).
+type Saver struct {
+	lock      sync.Mutex
+	db        *sql.DB
+	table     string
+	dialect   Dialect
+	batchSize int
+	pending   []pendingRecord
+	seq       uint64
+	stmts     map[int]*sql.Stmt
+
+	// Blobs restores spilled request bodies of exchanges(nil: such exchanges fail with saver.ErrBodyUnresolved).
+	Blobs saver.BlobStore
+
+	// MaxAttempts is the number of failed inserts of a record before it is dropped.
+	MaxAttempts int
+
+	// OnDeadLetter is called with a dropped record and its last error(nil: ignored).
+	OnDeadLetter func(r Record, e error)
+}
+
+// SaverNew creates a saver.
+//
+// # Arguments
+//   - db: A database(any driver).
+//   - table: A table created by Migrate.
+//   - d: The dialect of the database.
+//   - batchSize: Number of rows of a multi-row insert(1: insert immediately).
+//
+// A record is dropped after 3 failed inserts(see MaxAttempts).
+func SaverNew(db *sql.DB, table string, d Dialect, batchSize int) (*Saver, error) {
+	var e error = validTable(table)
+	if nil != e {
+		return nil, e
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &Saver{
+		db:          db,
+		table:       table,
+		dialect:     d,
+		batchSize:   batchSize,
+		pending:     make([]pendingRecord, 0, batchSize),
+		stmts:       make(map[int]*sql.Stmt),
+		MaxAttempts: 3,
+	}, nil
+}
+
+// stmt gets a prepared statement which inserts rows.
+func (s *Saver) stmt(ctx context.Context, rows int) (*sql.Stmt, error) {
+	stmt, found := s.stmts[rows]
+	if found {
+		return stmt, nil
+	}
+	query, e := s.dialect.InsertSQL(s.table, rows)
+	if nil != e {
+		return nil, e
+	}
+	stmt, e = s.db.PrepareContext(ctx, query)
+	if nil != e {
+		return nil, e
+	}
+	s.stmts[rows] = stmt
+	return stmt, nil
+}
+
+// insert inserts records using a multi-row insert.
+func (s *Saver) insert(ctx context.Context, records []pendingRecord) error {
+	stmt, e := s.stmt(ctx, len(records))
+	if nil != e {
+		return e
+	}
+	var args []any = make([]any, 0, len(records)*len(columns))
+	for _, r := range records {
+		args = append(args, r.args()...)
+	}
+	_, e = stmt.ExecContext(ctx, args...)
+	return e
+}
+
+// flush inserts buffered records(one by one if the batch insert fails).
+func (s *Saver) flush(ctx context.Context) error {
+	if 0 == len(s.pending) {
+		return nil
+	}
+	var e error = s.insert(ctx, s.pending)
+	if nil == e {
+		s.pending = s.pending[:0]
+		return nil
+	}
+
+	var failed []pendingRecord = s.pending[:1]
+	var errs []error = []error{e}
+	if 1 < len(s.pending) {
+		failed, errs = nil, nil
+		for _, r := range s.pending {
+			e = s.insert(ctx, []pendingRecord{r})
+			if nil != e {
+				failed = append(failed, r)
+				errs = append(errs, e)
+			}
+		}
+	}
+	var available bool = len(failed) < len(s.pending)
+	var kept []pendingRecord = s.pending[:0]
+	for i, r := range failed {
+		if available {
+			r.attempts += 1
+		}
+		if available && s.MaxAttempts <= r.attempts {
+			if nil != s.OnDeadLetter {
+				s.OnDeadLetter(r.Record, errs[i])
+			}
+			continue
+		}
+		kept = append(kept, r)
+	}
+	s.pending = kept
+	return errors.Join(errs...)
+}
+
+// Add buffers a record and inserts the batch if it is full.
+//
+// The record is dropped if it can not be inserted(the error is returned);
+// other failed records are kept for the next insert.
+func (s *Saver) Add(ctx context.Context, r Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.seq += 1
+	var seq uint64 = s.seq
+	s.pending = append(s.pending, pendingRecord{Record: r, seq: seq})
+	if len(s.pending) < s.batchSize {
+		return nil
+	}
+	var e error = s.flush(ctx)
+	var last int = len(s.pending) - 1
+	if 0 <= last && seq == s.pending[last].seq {
+		s.pending = s.pending[:last]
+		return e
+	}
+	return nil
+}
+
+// Flush inserts buffered records.
+func (s *Saver) Flush(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.flush(ctx)
+}
+
+// FlushEvery inserts buffered records periodically until the context is done.
+//
+// # Arguments
+//   - ctx: Stops the flush loop.
+//   - interval: The interval of inserts.
+//   - onError: Handles an insert error(failed records are kept for the next insert; see MaxAttempts).
+func (s *Saver) FlushEvery(ctx context.Context, interval time.Duration, onError func(error)) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var e error = s.Flush(ctx)
+			if nil != e {
+				onError(e)
+			}
+		}
+	}
+}
+
+// Close inserts buffered records and closes prepared statements.
+func (s *Saver) Close(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var e error = s.flush(ctx)
+	for rows, stmt := range s.stmts {
+		e = errors.Join(e, stmt.Close())
+		delete(s.stmts, rows)
+	}
+	return e
+}
+
+// AsExchangeSaver creates an exchange saver which returns the number of body bytes.
+func (s *Saver) AsExchangeSaver() saver.ExchangeSaver[int64] {
+	return func(x saver.ExchangeStd) (bytesCount int64, e error) {
+		x, e = saver.ExchangeResolveNew(s.Blobs)(x)
+		if nil != e {
+			return 0, e
+		}
+		r, e := RecordNew(x)
+		if nil != e {
+			return 0, e
+		}
+		return int64(len(r.Body)), s.Add(context.Background(), r)
+	}
+}
+
+// AsBytesSaver creates a saver of serialized exchanges(tar archives created by ExchangeStd2bytesTar).
+func (s *Saver) AsBytesSaver() saver.BytesSaver {
+	return saver.BytesSaver(saver.RequestSaverNew(
+		saver.ExchangeStdFromTar,
+		s.AsExchangeSaver(),
+	))
+}
@@ -0,0 +1,122 @@
+// Package sqldb provides a saver which inserts request records into a SQL table(database/sql).
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTable is returned when a table name is not a plain identifier.
+var ErrInvalidTable error = errors.New("invalid table name")
+
+var tableNamePattern *regexp.Regexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Dialect describes differences between databases.
+type Dialect struct {
+	// Placeholder gets the n-th(1-based) bind placeholder.
+	Placeholder func(n int) string
+
+	// TimeType is the column type of received_at.
+	TimeType string
+
+	// BlobType is the column type of the body.
+	BlobType string
+
+	// TextType is the column type of texts.
+	TextType string
+}
+
+// DialectSQLite uses "?" placeholders.
+var DialectSQLite Dialect = Dialect{
+	Placeholder: func(_ int) string { return "?" },
+	TimeType:    "TIMESTAMP",
+	BlobType:    "BLOB",
+	TextType:    "TEXT",
+}
+
+// DialectMySQL uses "?" placeholders.
+var DialectMySQL Dialect = Dialect{
+	Placeholder: func(_ int) string { return "?" },
+	TimeType:    "DATETIME(6)",
+	BlobType:    "LONGBLOB",
+	TextType:    "LONGTEXT",
+}
+
+// DialectPostgres uses "$n" placeholders.
+var DialectPostgres Dialect = Dialect{
+	Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	TimeType:    "TIMESTAMPTZ",
+	BlobType:    "BYTEA",
+	TextType:    "TEXT",
+}
+
+// columns of a request record.
+var columns []string = []string{"id", "received_at", "method", "path", "headers", "body", "checksum"}
+
+func validTable(table string) error {
+	if !tableNamePattern.MatchString(table) {
+		return fmt.Errorf("%w: %s", ErrInvalidTable, table)
+	}
+	return nil
+}
+
+// CreateTableSQL gets a CREATE TABLE statement of a request table.
+func (d Dialect) CreateTableSQL(table string) (string, error) {
+	var e error = validTable(table)
+	if nil != e {
+		return "", e
+	}
+	return strings.Join([]string{
+		"CREATE TABLE IF NOT EXISTS " + table + " (",
+		"id VARCHAR(64) PRIMARY KEY, ",
+		"received_at " + d.TimeType + " NOT NULL, ",
+		"method VARCHAR(16) NOT NULL, ",
+		"path " + d.TextType + " NOT NULL, ",
+		"headers " + d.TextType + " NOT NULL, ",
+		"body " + d.BlobType + ", ",
+		"checksum CHAR(64) NOT NULL",
+		")",
+	}, ""), nil
+}
+
+// InsertSQL gets a multi-row INSERT statement.
+//
+// # Arguments
+//   - table: The table name.
+//   - rows: Number of rows.
+func (d Dialect) InsertSQL(table string, rows int) (string, error) {
+	var e error = validTable(table)
+	if nil != e {
+		return "", e
+	}
+	var b strings.Builder
+	_, _ = b.WriteString("INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES ")
+	var n int = 1
+	for row := 0; row < rows; row++ {
+		if 0 < row {
+			_, _ = b.WriteString(", ")
+		}
+		var placeholders []string = make([]string, 0, len(columns))
+		for range columns {
+			placeholders = append(placeholders, d.Placeholder(n))
+			n += 1
+		}
+		_, _ = b.WriteString("(" + strings.Join(placeholders, ", ") + ")")
+	}
+	return b.String(), nil
+}
+
+// Migrate creates a request table if it does not exist.
+func Migrate(ctx context.Context, db *sql.DB, table string, d Dialect) error {
+	query, e := d.CreateTableSQL(table)
+	if nil != e {
+		return e
+	}
+	_, e = db.ExecContext(ctx, query)
+	return e
+}
@@ -0,0 +1,329 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+	"github.com/takanoriyanagitani/go-simple-req-saver/sqldb"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+// testExec is an executed statement.
+type testExec struct {
+	query string
+	args  []driver.Value
+}
+
+// testDB is a fake driver which records statements.
+type testDB struct {
+	lock     sync.Mutex
+	prepared []string
+	execs    []testExec
+	failures int
+	rejected string // the id of a row which can never be inserted
+}
+
+func (d *testDB) Open(_ string) (driver.Conn, error) { return &testConn{db: d}, nil }
+
+type testConn struct{ db *testDB }
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.lock.Lock()
+	defer c.db.lock.Unlock()
+	c.db.prepared = append(c.db.prepared, query)
+	return &testStmt{db: c.db, query: query}, nil
+}
+
+func (c *testConn) Close() error              { return nil }
+func (c *testConn) Begin() (driver.Tx, error) { return nil, errors.New("unsupported") }
+
+type testStmt struct {
+	db    *testDB
+	query string
+}
+
+func (s *testStmt) Close() error  { return nil }
+func (s *testStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s *testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+	if 0 < s.db.failures {
+		s.db.failures--
+		return nil, errors.New("database down")
+	}
+	for i := 0; i < len(args); i += 7 {
+		if s.db.rejected == args[i] {
+			return nil, errors.New("duplicate key")
+		}
+	}
+	s.db.execs = append(s.db.execs, testExec{query: s.query, args: args})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *testStmt) Query(_ []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("unsupported")
+}
+
+var testDriverCount atomic.Int64
+
+func testDBNew(t *testing.T) (*testDB, *sql.DB) {
+	var d *testDB = &testDB{}
+	var name string = "sqldb-test-" + strconv.FormatInt(testDriverCount.Add(1), 10)
+	sql.Register(name, d)
+	db, e := sql.Open(name, "")
+	if nil != e {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return d, db
+}
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InsertSQL", func(t *testing.T) {
+		t.Parallel()
+
+		query, e := sqldb.DialectPostgres.InsertSQL("reqs", 2)
+		t.Run("no error", assertNil(e))
+		t.Run("query", assertEq(query, "INSERT INTO reqs "+
+			"(id, received_at, method, path, headers, body, checksum) VALUES "+
+			"($1, $2, $3, $4, $5, $6, $7), ($8, $9, $10, $11, $12, $13, $14)"))
+
+		query, _ = sqldb.DialectSQLite.InsertSQL("main.reqs", 1)
+		t.Run("sqlite", assertTrue(strings.HasSuffix(query, "(?, ?, ?, ?, ?, ?, ?)")))
+	})
+
+	t.Run("invalid table", func(t *testing.T) {
+		t.Parallel()
+
+		_, e := sqldb.DialectMySQL.CreateTableSQL("reqs; DROP TABLE x")
+		t.Run("rejected", assertTrue(errors.Is(e, sqldb.ErrInvalidTable)))
+	})
+
+	t.Run("Migrate", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		var e error = sqldb.Migrate(context.Background(), db, "reqs", sqldb.DialectSQLite)
+		t.Run("no error", assertNil(e))
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		t.Run("executed", assertEq(len(d.execs), 1))
+		t.Run("create", assertTrue(strings.HasPrefix(d.execs[0].query, "CREATE TABLE IF NOT EXISTS reqs (")))
+		t.Run("blob", assertTrue(strings.Contains(d.execs[0].query, "body BLOB")))
+	})
+}
+
+func TestSaver(t *testing.T) {
+	t.Parallel()
+
+	var received time.Time = time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)
+	var exchangeNew func(id string) saver.ExchangeStd = func(id string) saver.ExchangeStd {
+		var q = httptest.NewRequest("POST", "/items?x=1", nil)
+		q.Header.Set("Content-Type", "text/plain")
+		return saver.ExchangeStdNew(q, []byte("hw"), id, received)
+	}
+
+	t.Run("batched", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		s, e := sqldb.SaverNew(db, "reqs", sqldb.DialectSQLite, 2)
+		t.Run("no saver error", assertNil(e))
+
+		var sav saver.ExchangeSaver[int64] = s.AsExchangeSaver()
+		for _, id := range []string{"a", "b", "c"} {
+			cnt, e := sav(exchangeNew(id))
+			t.Run("no save error", assertNil(e))
+			t.Run("bytes count", assertEq(cnt, 2))
+		}
+		t.Run("no close error", assertNil(s.Close(context.Background())))
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		t.Run("prepared per row count", assertEq(len(d.prepared), 2))
+		t.Run("executed", assertEq(len(d.execs), 2))
+		t.Run("first batch", assertEq(len(d.execs[0].args), 14))
+		t.Run("rest", assertEq(len(d.execs[1].args), 7))
+
+		var row []driver.Value = d.execs[1].args
+		t.Run("id", assertEq(row[0].(string), "c"))
+		t.Run("received", assertTrue(received.Equal(row[1].(time.Time))))
+		t.Run("method", assertEq(row[2].(string), "POST"))
+		t.Run("path", assertEq(row[3].(string), "/items?x=1"))
+		t.Run("headers", assertEq(row[4].(string), `{"Content-Type":["text/plain"]}`))
+		t.Run("body", assertEq(string(row[5].([]byte)), "hw"))
+		t.Run("checksum", assertEq(
+			row[6].(string),
+			"91660cd41bd4fe159351ab036b7ca3e998602a9fec70b362ca11e0177fe706e3",
+		))
+	})
+
+	t.Run("serialized", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		s, _ := sqldb.SaverNew(db, "reqs", sqldb.DialectSQLite, 8)
+		serialized, _ := saver.ExchangeStd2bytesTar(exchangeNew("tarred"))
+		_, e := s.AsBytesSaver()(serialized)
+		t.Run("no save error", assertNil(e))
+
+		d.lock.Lock()
+		t.Run("buffered", assertEq(len(d.execs), 0))
+		d.lock.Unlock()
+
+		t.Run("no flush error", assertNil(s.Flush(context.Background())))
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		t.Run("flushed", assertEq(len(d.execs), 1))
+		t.Run("id", assertEq(d.execs[0].args[0].(string), "tarred"))
+	})
+
+	t.Run("failed insert", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		s, _ := sqldb.SaverNew(db, "reqs", sqldb.DialectSQLite, 2)
+		var sav saver.ExchangeSaver[int64] = s.AsExchangeSaver()
+		_, e := sav(exchangeNew("accepted"))
+		t.Run("buffered", assertNil(e))
+
+		d.lock.Lock()
+		d.failures = 3 // the batch and both rows
+		d.lock.Unlock()
+		_, e = sav(exchangeNew("rejected"))
+		t.Run("insert error", assertTrue(nil != e))
+
+		_, e = sav(exchangeNew("next"))
+		t.Run("retried", assertNil(e))
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		t.Run("executed", assertEq(len(d.execs), 1))
+		t.Run("accepted kept", assertEq(d.execs[0].args[0].(string), "accepted"))
+		t.Run("rejected dropped", assertEq(d.execs[0].args[7].(string), "next"))
+	})
+
+	t.Run("failing row", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		d.rejected = "dup"
+		s, _ := sqldb.SaverNew(db, "reqs", sqldb.DialectSQLite, 2)
+		s.MaxAttempts = 2
+		var dead []string
+		s.OnDeadLetter = func(r sqldb.Record, _ error) { dead = append(dead, r.ID) }
+		var sav saver.ExchangeSaver[int64] = s.AsExchangeSaver()
+
+		for _, id := range []string{"dup", "a", "b", "c", "d"} {
+			_, e := sav(exchangeNew(id))
+			t.Run("no save error "+id, assertNil(e))
+		}
+		t.Run("dead letter", assertEq(strings.Join(dead, ","), "dup"))
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		var ids []string
+		for _, x := range d.execs {
+			for i := 0; i < len(x.args); i += 7 {
+				ids = append(ids, x.args[i].(string))
+			}
+		}
+		t.Run("inserted", assertEq(strings.Join(ids, ","), "a,b,c,d"))
+	})
+
+	t.Run("FlushEvery", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		s, _ := sqldb.SaverNew(db, "reqs", sqldb.DialectSQLite, 100)
+		ctx, cancel := context.WithCancel(context.Background())
+		var done chan struct{} = make(chan struct{})
+		go func() {
+			defer close(done)
+			s.FlushEvery(ctx, time.Millisecond, func(e error) { t.Error(e) })
+		}()
+		_, _ = s.AsExchangeSaver()(exchangeNew("idle"))
+
+		var executed int
+		for i := 0; i < 1000 && 0 == executed; i++ {
+			time.Sleep(time.Millisecond)
+			d.lock.Lock()
+			executed = len(d.execs)
+			d.lock.Unlock()
+		}
+		cancel()
+		<-done
+		t.Run("flushed", assertEq(executed, 1))
+	})
+
+	t.Run("no id or time", func(t *testing.T) {
+		t.Parallel()
+
+		r, e := sqldb.RecordNew(exchangeNew(""))
+		t.Run("no error", assertNil(e))
+		t.Run("generated", assertEq(len(r.ID), 32))
+		other, _ := sqldb.RecordNew(exchangeNew(""))
+		t.Run("unique", assertTrue(r.ID != other.ID))
+
+		var x saver.ExchangeStd = exchangeNew("")
+		x.Time = time.Time{}
+		r, _ = sqldb.RecordNew(x)
+		t.Run("insert time", assertTrue(!r.ReceivedAt.IsZero()))
+	})
+
+	t.Run("spilled body", func(t *testing.T) {
+		t.Parallel()
+
+		d, db := testDBNew(t)
+		s, _ := sqldb.SaverNew(db, "reqs", sqldb.DialectSQLite, 1)
+		blobs, _ := saver.BlobDirNew(t.TempDir())
+		serialized, _ := saver.ExchangeStd2bytesSpillNew(blobs, 0, saver.ExchangeStd2bytesTar)(exchangeNew("spilled"))
+
+		_, e := s.AsBytesSaver()(serialized)
+		t.Run("unresolved", assertTrue(errors.Is(e, saver.ErrBodyUnresolved)))
+
+		s.Blobs = blobs
+		_, e = s.AsBytesSaver()(serialized)
+		t.Run("no save error", assertNil(e))
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		t.Run("body", assertEq(string(d.execs[0].args[5].([]byte)), "hw"))
+	})
+}
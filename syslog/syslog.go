@@ -0,0 +1,211 @@
+// Package syslog provides a saver which emits RFC 5424 syslog messages summarizing saved requests.
+//
+// Bodies are not sent; a message carries the method, path, size, record id and client ip.
+package syslog
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// SDID is the id of the structured data element(32473 is the example enterprise number).
+const SDID = "request@32473"
+
+// PriorityDefault is local0.info.
+const PriorityDefault = 16*8 + 6
+
+// Summary is a summary of a saved request.
+type Summary struct {
+	ID       string
+	Time     time.Time
+	Method   string
+	Path     string
+	Size     int64
+	ClientIP string
+}
+
+// SummaryNew creates a summary of an exchange.
+func SummaryNew(x saver.ExchangeStd) Summary {
+	path, _, _ := strings.Cut(x.URI, "?")
+	client, _, e := net.SplitHostPort(x.RemoteAddr)
+	if nil != e {
+		client = x.RemoteAddr
+	}
+	var size int64 = int64(len(saver.Request[http.Header, []byte](x.Request).Body()))
+	if nil != x.BodyRef {
+		size = x.BodyRef.Size // a spilled body
+	}
+	return Summary{
+		ID:       x.ID,
+		Time:     x.Time,
+		Method:   x.Method,
+		Path:     path,
+		Size:     size,
+		ClientIP: client,
+	}
+}
+
+// sdEscape escapes a structured data param value.
+var sdEscape *strings.Replacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// header gets a header field or the nil value("-").
+func header(value string, limit int) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r < 33 || 126 < r {
+			continue // printable US-ASCII only
+		}
+		if limit <= b.Len() {
+			break
+		}
+		_, _ = b.WriteRune(r) // always nil error
+	}
+	if 0 == b.Len() {
+		return "-"
+	}
+	return b.String()
+}
+
+// Writer sends messages to a syslog server.
+//
+// TCP uses octet-counting framing(RFC 6587); a stream unix socket(a local daemon) gets newline-terminated messages;
+// datagram sockets(udp, unixgram) send a message per datagram.
+type Writer struct {
+	// Hostname is the HOSTNAME field.
+	Hostname string
+
+	// AppName is the APP-NAME field.
+	AppName string
+
+	// Priority is facility * 8 + severity.
+	Priority int
+
+	lock    sync.Mutex
+	network string
+	address string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+// WriterNew creates a writer which connects on demand.
+//
+// # Arguments
+//   - network: "udp", "tcp", "unix" or "unixgram".
+//   - address: The server address(sample: 127.0.0.1:514, /dev/log).
+//   - timeout: Deadline of a dial or a write(0: no deadline).
+func WriterNew(network, address string, timeout time.Duration) *Writer {
+	hostname, _ := os.Hostname() // the nil value is used on error
+	return &Writer{
+		Hostname: hostname,
+		AppName:  "req-saver",
+		Priority: PriorityDefault,
+		network:  network,
+		address:  address,
+		timeout:  timeout,
+	}
+}
+
+// frame frames a message for the network.
+func (w *Writer) frame(msg []byte) []byte {
+	switch w.network {
+	case "tcp", "tcp4", "tcp6":
+		return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	case "unix":
+		return append(msg[:len(msg):len(msg)], '\n')
+	default:
+		return msg
+	}
+}
+
+// Format gets a syslog message of a summary.
+func (w *Writer) Format(s Summary) []byte {
+	var timestamp string = "-"
+	if !s.Time.IsZero() {
+		timestamp = s.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	var b strings.Builder
+	_, _ = b.WriteString("<" + strconv.Itoa(w.Priority) + ">1 ") // always nil error
+	_, _ = b.WriteString(timestamp + " ")
+	_, _ = b.WriteString(header(w.Hostname, 255) + " ")
+	_, _ = b.WriteString(header(w.AppName, 48) + " ")
+	_, _ = b.WriteString(strconv.Itoa(os.Getpid()) + " ")
+	_, _ = b.WriteString("request [" + SDID)
+	for _, param := range [][2]string{
+		{"method", s.Method},
+		{"path", s.Path},
+		{"size", strconv.FormatInt(s.Size, 10)},
+		{"id", s.ID},
+		{"client", s.ClientIP},
+	} {
+		_, _ = b.WriteString(" " + param[0] + `="` + sdEscape.Replace(param[1]) + `"`)
+	}
+	_, _ = b.WriteString("] saved " + s.Method + " " + s.Path)
+	return []byte(b.String())
+}
+
+func (w *Writer) send(msg []byte) error {
+	if nil == w.conn {
+		conn, e := net.DialTimeout(w.network, w.address, w.timeout)
+		if nil != e {
+			return e
+		}
+		w.conn = conn
+	}
+	if 0 < w.timeout {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout)) // errors are returned by Write
+	}
+	_, e := w.conn.Write(w.frame(msg))
+	if nil != e {
+		e = errors.Join(e, w.conn.Close())
+		w.conn = nil
+	}
+	return e
+}
+
+// Send sends a message of a summary; a broken connection is replaced once.
+func (w *Writer) Send(s Summary) error {
+	var msg []byte = w.Format(s)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var e error = w.send(msg)
+	if nil == e {
+		return nil
+	}
+	return w.send(msg)
+}
+
+// Close closes the connection.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if nil == w.conn {
+		return nil
+	}
+	var e error = w.conn.Close()
+	w.conn = nil
+	return e
+}
+
+// ExchangeSaverNew creates a saver which sends a summary of each exchange and returns the body size.
+func ExchangeSaverNew(w *Writer) saver.ExchangeSaver[int64] {
+	return func(x saver.ExchangeStd) (bodySize int64, e error) {
+		var s Summary = SummaryNew(x)
+		return s.Size, w.Send(s)
+	}
+}
+
+// SaverNew creates a saver of serialized exchanges(tar archives created by ExchangeStd2bytesTar).
+func SaverNew(w *Writer) saver.BytesSaver {
+	return saver.BytesSaver(saver.RequestSaverNew(
+		saver.ExchangeStdFromTar,
+		ExchangeSaverNew(w),
+	))
+}
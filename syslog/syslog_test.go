@@ -0,0 +1,191 @@
+package syslog_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+	"github.com/takanoriyanagitani/go-simple-req-saver/syslog"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+func testExchangeNew() saver.ExchangeStd {
+	var q = httptest.NewRequest("POST", "/items?token=secret", nil)
+	q.RemoteAddr = "192.0.2.7:50000"
+	return saver.ExchangeStdNew(q, []byte("hello"), "id-1", time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC))
+}
+
+// testReadFrame reads an octet-counted message.
+func testReadFrame(rdr *bufio.Reader) (string, error) {
+	size, e := rdr.ReadString(' ')
+	if nil != e {
+		return "", e
+	}
+	n, e := strconv.Atoi(strings.TrimSuffix(size, " "))
+	if nil != e {
+		return "", e
+	}
+	var msg []byte = make([]byte, n)
+	_, e = io.ReadFull(rdr, msg)
+	return string(msg), e
+}
+
+// testReadLine reads a newline-terminated message.
+func testReadLine(rdr *bufio.Reader) (string, error) {
+	line, e := rdr.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), e
+}
+
+// testStreamServerNew accepts a connection and sends received messages.
+func testStreamServerNew(t *testing.T, l net.Listener, read func(*bufio.Reader) (string, error)) <-chan string {
+	t.Cleanup(func() { _ = l.Close() })
+	var messages chan string = make(chan string, 16)
+	go func() {
+		for {
+			conn, e := l.Accept()
+			if nil != e {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var rdr *bufio.Reader = bufio.NewReader(conn)
+				for {
+					msg, e := read(rdr)
+					if nil != e {
+						return
+					}
+					messages <- msg
+				}
+			}()
+		}
+	}()
+	return messages
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	var expected string = "<134>1 2026-10-17T13:00:00.000000Z host req-saver " + strconv.Itoa(os.Getpid()) + " request " +
+		`[request@32473 method="POST" path="/items" size="5" id="id-1" client="192.0.2.7"] saved POST /items`
+
+	t.Run("Format", func(t *testing.T) {
+		t.Parallel()
+
+		var w *syslog.Writer = syslog.WriterNew("udp", "127.0.0.1:0", time.Second)
+		w.Hostname = "host"
+		var msg string = string(w.Format(syslog.SummaryNew(testExchangeNew())))
+		t.Run("message", assertEq(msg, expected))
+		t.Run("no query", assertTrue(!strings.Contains(msg, "secret")))
+		t.Run("no body", assertTrue(!strings.Contains(msg, "hello")))
+
+		var spilled saver.ExchangeStd = testExchangeNew()
+		spilled.BodyRef = &saver.BlobRef{Digest: strings.Repeat("0", 64), Size: 1 << 20}
+		t.Run("spilled size", assertEq(syslog.SummaryNew(spilled).Size, 1<<20))
+
+		msg = string(w.Format(syslog.Summary{Path: `a"b]\`}))
+		t.Run("escaped", assertTrue(strings.Contains(msg, `path="a\"b\]\\"`)))
+
+		w.Hostname = ""
+		msg = string(w.Format(syslog.Summary{}))
+		t.Run("nil values", assertTrue(strings.HasPrefix(msg, "<134>1 - - req-saver ")))
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		t.Parallel()
+
+		pc, e := net.ListenPacket("udp", "127.0.0.1:0")
+		t.Run("no listen error", assertNil(e))
+		defer pc.Close()
+
+		var w *syslog.Writer = syslog.WriterNew("udp", pc.LocalAddr().String(), time.Second)
+		defer w.Close()
+		w.Hostname = "host"
+		serialized, _ := saver.ExchangeStd2bytesTar(testExchangeNew())
+		cnt, e := syslog.SaverNew(w)(serialized)
+		t.Run("no save error", assertNil(e))
+		t.Run("body size", assertEq(cnt, 5))
+
+		var buf []byte = make([]byte, 2048)
+		_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, e := pc.ReadFrom(buf)
+		t.Run("no read error", assertNil(e))
+		t.Run("datagram", assertEq(string(buf[:n]), expected))
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		t.Parallel()
+
+		l, e := net.Listen("tcp", "127.0.0.1:0")
+		t.Run("no listen error", assertNil(e))
+		var messages <-chan string = testStreamServerNew(t, l, testReadFrame)
+
+		var w *syslog.Writer = syslog.WriterNew("tcp", l.Addr().String(), time.Second)
+		defer w.Close()
+		w.Hostname = "host"
+		var sav saver.ExchangeSaver[int64] = syslog.ExchangeSaverNew(w)
+		for i := 0; i < 2; i++ {
+			_, e = sav(testExchangeNew())
+			t.Run("no save error", assertNil(e))
+			t.Run("octet counted", assertEq(<-messages, expected))
+		}
+	})
+
+	t.Run("unix", func(t *testing.T) {
+		t.Parallel()
+
+		dir, e := os.MkdirTemp("", "syslog")
+		t.Run("no temp error", assertNil(e))
+		defer os.RemoveAll(dir)
+		var name string = filepath.Join(dir, "log.sock")
+
+		l, e := net.Listen("unix", name)
+		t.Run("no listen error", assertNil(e))
+		var messages <-chan string = testStreamServerNew(t, l, testReadLine)
+
+		var w *syslog.Writer = syslog.WriterNew("unix", name, time.Second)
+		defer w.Close()
+		w.Hostname = "host"
+		for i := 0; i < 2; i++ {
+			_, e = syslog.ExchangeSaverNew(w)(testExchangeNew())
+			t.Run("no save error", assertNil(e))
+			t.Run("newline terminated", assertEq(<-messages, expected))
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		t.Parallel()
+
+		var w *syslog.Writer = syslog.WriterNew("unix", filepath.Join(t.TempDir(), "nosuch"), time.Second)
+		_, e := syslog.ExchangeSaverNew(w)(testExchangeNew())
+		t.Run("error", assertTrue(nil != e))
+	})
+}
@@ -0,0 +1,110 @@
+package saver
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RecordingTransport is a http.RoundTripper which saves outgoing requests(and responses).
+//
+// The real transport gets the whole request body.
+type RecordingTransport struct {
+	// Base sends requests(http.DefaultTransport if nil).
+	Base http.RoundTripper
+
+	// CaptureResponse saves a request with its response after the response body is closed.
+	// A request is saved before it is sent if false.
+	CaptureResponse bool
+
+	serializer    ExchangeStd2bytes
+	saver         BytesSaver
+	requestLimit  int64
+	responseLimit int64
+	idGen         RecordIDGen
+	onError       func(e error)
+}
+
+// RecordingTransportNew creates a recording transport which saves requests only.
+//
+// # Arguments
+//   - serializer: Serializes an exchange(sample: ExchangeStd2bytesTar).
+//   - saver: Saves a serialized exchange.
+//   - requestLimit: Number of request body bytes to save.
+//   - responseLimit: Number of response body bytes to save.
+//   - idGen: Creates a record id.
+//   - onError: Reports save failures(requests are sent anyway).
+func RecordingTransportNew(
+	serializer ExchangeStd2bytes,
+	saver BytesSaver,
+	requestLimit, responseLimit int64,
+	idGen RecordIDGen,
+	onError func(e error),
+) *RecordingTransport {
+	return &RecordingTransport{
+		serializer:    serializer,
+		saver:         saver,
+		requestLimit:  requestLimit,
+		responseLimit: responseLimit,
+		idGen:         idGen,
+		onError:       onError,
+	}
+}
+
+func (t *RecordingTransport) save(x ExchangeStd) {
+	_, e := Compose(t.serializer, t.saver)(x)
+	if nil != e {
+		t.onError(e)
+	}
+}
+
+func (t *RecordingTransport) base() http.RoundTripper {
+	if nil == t.Base {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// RoundTrip saves a request and sends it using the Base.
+func (t *RecordingTransport) RoundTrip(q *http.Request) (*http.Response, error) {
+	var sent time.Time = time.Now()
+	var out *http.Request = q.Clone(q.Context())
+	captured, e := bodyCapture(out, t.requestLimit)
+	if nil != e {
+		// a RoundTripper must close the request body even on errors
+		return nil, errors.Join(e, q.Body.Close())
+	}
+	var x ExchangeStd = ExchangeStdNew(out, bytes.Clone(captured), t.idGen(), sent)
+
+	if !t.CaptureResponse {
+		t.save(x)
+		return t.base().RoundTrip(out)
+	}
+
+	res, e := t.base().RoundTrip(out)
+	if nil != e {
+		t.save(x)
+		return res, e
+	}
+	var once sync.Once
+	var status int = res.StatusCode
+	var header http.Header = res.Header.Clone()
+	res.Body = &captureBody{
+		ReadCloser: res.Body,
+		limit:      t.responseLimit,
+		onClose: func(body []byte) {
+			once.Do(func() {
+				x.Response = &ResponseStd{
+					Status:  status,
+					Header:  header,
+					Body:    bytes.Clone(body),
+					Latency: time.Since(sent),
+				}
+				t.save(x)
+			})
+		},
+	}
+	return res, nil
+}
@@ -0,0 +1,106 @@
+package saver_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+// testBrokenBody fails reads and counts closes.
+type testBrokenBody struct{ closed int }
+
+func (b *testBrokenBody) Read(_ []byte) (int, error) { return 0, errors.New("broken body") }
+func (b *testBrokenBody) Close() error               { b.closed++; return nil }
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var partner *httptest.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, q *http.Request) {
+			body, _ := io.ReadAll(q.Body)
+			w.WriteHeader(202)
+			_, _ = w.Write([]byte("ack:" + string(body)))
+		},
+	))
+	t.Cleanup(partner.Close)
+
+	var newClient func(captureResponse bool) (*http.Client, func() [][]byte) = func(
+		captureResponse bool,
+	) (*http.Client, func() [][]byte) {
+		var lock sync.Mutex
+		var saved [][]byte
+		var sav saver.BytesSaver = func(serialized []byte) (int64, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			saved = append(saved, bytes.Clone(serialized))
+			return int64(len(serialized)), nil
+		}
+		var rt *saver.RecordingTransport = saver.RecordingTransportNew(
+			saver.ExchangeStd2bytesTar,
+			sav,
+			1024,
+			1024,
+			saver.RecordIDGenRandom,
+			func(e error) { panic(e) },
+		)
+		rt.CaptureResponse = captureResponse
+		return &http.Client{Transport: rt}, func() [][]byte {
+			lock.Lock()
+			defer lock.Unlock()
+			return saved
+		}
+	}
+
+	t.Run("request only", func(t *testing.T) {
+		t.Parallel()
+
+		client, saved := newClient(false)
+		res, e := client.Post(partner.URL+"/orders", "application/json", strings.NewReader(`{"id":1}`))
+		t.Run("no request error", assertNil(e))
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		t.Run("body sent", assertEq(string(body), `ack:{"id":1}`))
+
+		t.Run("saved", assertEq(len(saved()), 1))
+		x, e := saver.ExchangeStdFromTar(saved()[0])
+		t.Run("no parse error", assertNil(e))
+		t.Run("uri", assertEq(x.URI, "/orders"))
+		t.Run("body", assertEq(string(saver.Request[http.Header, []byte](x.Request).Body()), `{"id":1}`))
+		t.Run("no response", assertTrue(nil == x.Response))
+	})
+
+	t.Run("with response", func(t *testing.T) {
+		t.Parallel()
+
+		client, saved := newClient(true)
+		res, e := client.Post(partner.URL+"/orders", "application/json", strings.NewReader(`{"id":2}`))
+		t.Run("no request error", assertNil(e))
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+
+		t.Run("saved", assertEq(len(saved()), 1))
+		x, e := saver.ExchangeStdFromTar(saved()[0])
+		t.Run("no parse error", assertNil(e))
+		t.Run("status", assertEq(x.Response.Status, 202))
+		t.Run("response body", assertEq(string(x.Response.Body), `ack:{"id":2}`))
+	})
+
+	t.Run("broken body", func(t *testing.T) {
+		t.Parallel()
+
+		client, saved := newClient(false)
+		var body *testBrokenBody = &testBrokenBody{}
+		q, _ := http.NewRequest("POST", partner.URL+"/orders", body)
+		_, e := client.Transport.RoundTrip(q)
+		t.Run("error", assertTrue(nil != e))
+		t.Run("closed", assertEq(body.closed, 1))
+		t.Run("not saved", assertEq(len(saved()), 0))
+	})
+}
@@ -0,0 +1,190 @@
+package saver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// VCRKey gets a key to match a request with recorded requests.
+type VCRKey func(method, uri string, body []byte) (key string)
+
+// VCRKeyNew creates a key which contains selected parts of a request.
+//
+// # Arguments
+//   - method: Uses the method.
+//   - path: Uses the path.
+//   - query: Uses the normalized(sorted) query.
+//   - body: Uses the SHA-256 of the body.
+func VCRKeyNew(method, path, query, body bool) VCRKey {
+	return func(m, uri string, b []byte) (key string) {
+		var parts []string = make([]string, 0, 4)
+		var u *url.URL = &url.URL{}
+		if parsed, e := url.ParseRequestURI(uri); nil == e {
+			u = parsed
+		}
+		if method {
+			parts = append(parts, m)
+		}
+		if path {
+			parts = append(parts, u.Path)
+		}
+		if query {
+			parts = append(parts, u.Query().Encode())
+		}
+		if body {
+			var digest [sha256.Size]byte = sha256.Sum256(b)
+			parts = append(parts, hex.EncodeToString(digest[:]))
+		}
+		return strings.Join(parts, " ")
+	}
+}
+
+// VCRKeyDefault matches the method, the path, the query and the body.
+var VCRKeyDefault VCRKey = VCRKeyNew(true, true, true, true)
+
+type vcrTrack struct {
+	responses []*ResponseStd
+	next      int
+}
+
+// VCR answers requests with recorded responses.
+//
+// Recorded responses of the same key are returned in the recorded order; the last one is repeated.
+type VCR struct {
+	lock   sync.Mutex
+	key    VCRKey
+	limit  int64
+	tracks map[string]*vcrTrack
+
+	// Fallback handles unmatched requests(nil: 404).
+	Fallback http.Handler
+
+	// Blobs restores request bodies spilled by ExchangeStd2bytesSpillNew(nil: Load fails with ErrBodyUnresolved).
+	Blobs BlobStore
+}
+
+// VCRNew creates a VCR.
+//
+// # Arguments
+//   - key: Gets a matching key.
+//   - limit: Number of request body bytes to match.
+func VCRNew(key VCRKey, limit int64) *VCR {
+	return &VCR{
+		key:    key,
+		limit:  limit,
+		tracks: make(map[string]*vcrTrack),
+	}
+}
+
+// Add adds a recorded exchange; an exchange without a response is ignored.
+//
+// A spilled request body must be resolved before(see Load).
+func (v *VCR) Add(x ExchangeStd) {
+	if nil == x.Response {
+		return
+	}
+	var body []byte = Request[http.Header, []byte](x.Request).Body()
+	var key string = v.key(x.Method, x.URI, body)
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	track, found := v.tracks[key]
+	if !found {
+		track = &vcrTrack{}
+		v.tracks[key] = track
+	}
+	track.responses = append(track.responses, x.Response)
+}
+
+// Load adds all exchanges from a source; spilled request bodies are restored using Blobs.
+func (v *VCR) Load(src ExchangeSource) error {
+	for {
+		x, e := src()
+		if errors.Is(e, io.EOF) {
+			return nil
+		}
+		if nil == e {
+			x, e = ExchangeResolveNew(v.Blobs)(x)
+		}
+		if nil != e {
+			return e
+		}
+		v.Add(x)
+	}
+}
+
+func (v *VCR) match(key string) (res *ResponseStd, found bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	track, found := v.tracks[key]
+	if !found {
+		return nil, false
+	}
+	res = track.responses[track.next]
+	if track.next < len(track.responses)-1 {
+		track.next += 1
+	}
+	return res, true
+}
+
+func (v *VCR) ServeHTTP(w http.ResponseWriter, q *http.Request) {
+	captured, e := bodyCapture(q, v.limit)
+	if nil != e {
+		http.Error(w, e.Error(), http.StatusBadRequest)
+		return
+	}
+	res, found := v.match(v.key(q.Method, q.URL.RequestURI(), captured))
+	if !found {
+		if nil == v.Fallback {
+			http.NotFound(w, q)
+			return
+		}
+		v.Fallback.ServeHTTP(w, q)
+		return
+	}
+	for key, values := range res.Header {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.Header().Del("Content-Length") // a saved body can be truncated
+	w.WriteHeader(res.Status)
+	_, _ = w.Write(res.Body) // headers already sent
+}
+
+// RecordingFallbackNew creates a recording proxy which handles unmatched requests.
+//
+// Exchanges recorded by the proxy are added to the VCR and then passed to enqueue.
+// The proxy should be set to Fallback.
+//
+// # Arguments
+//   - upstream: The base url of an upstream.
+//   - enqueue: Queues an exchange to save(nil: no save).
+//   - responseLimit: Number of response body bytes to record.
+//   - idGen: Creates a record id.
+//   - onError: Reports proxy errors and enqueue errors.
+func (v *VCR) RecordingFallbackNew(
+	upstream *url.URL,
+	enqueue func(x ExchangeStd) error,
+	responseLimit int64,
+	idGen RecordIDGen,
+	onError func(e error),
+) *RecordingProxy {
+	return RecordingProxyNew(
+		upstream,
+		func(x ExchangeStd) error {
+			v.Add(x)
+			if nil == enqueue {
+				return nil
+			}
+			return enqueue(x)
+		},
+		v.limit,
+		responseLimit,
+		idGen,
+		onError,
+	)
+}
@@ -0,0 +1,119 @@
+package saver_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+func testVCRRecorded(uri, body string, status int, resBody string) []byte {
+	var q *http.Request = httptest.NewRequest("POST", uri, nil)
+	var x saver.ExchangeStd = saver.ExchangeStdNew(q, []byte(body), "", time.Time{})
+	x.Response = &saver.ResponseStd{
+		Status: status,
+		Header: http.Header{"Content-Type": {"text/plain"}},
+		Body:   []byte(resBody),
+	}
+	serialized, e := saver.ExchangeStd2bytesTar(x)
+	if nil != e {
+		panic(e)
+	}
+	return serialized
+}
+
+func TestVCR(t *testing.T) {
+	t.Parallel()
+
+	t.Run("VCRKeyNew", func(t *testing.T) {
+		t.Parallel()
+
+		var key saver.VCRKey = saver.VCRKeyNew(true, true, true, false)
+		t.Run("normalized query", assertEq(
+			key("GET", "/items?b=2&a=1", nil),
+			key("GET", "/items?a=1&b=2", nil),
+		))
+		t.Run("different method", assertTrue(key("GET", "/", nil) != key("POST", "/", nil)))
+
+		var withBody saver.VCRKey = saver.VCRKeyDefault
+		t.Run("body hash", assertTrue(withBody("POST", "/", []byte("a")) != withBody("POST", "/", []byte("b"))))
+	})
+
+	t.Run("playback", func(t *testing.T) {
+		t.Parallel()
+
+		var v *saver.VCR = saver.VCRNew(saver.VCRKeyDefault, 1024)
+		e := v.Load(saver.ExchangeSourceNewBlobs([][]byte{
+			testVCRRecorded("/items?a=1&b=2", "x", 201, "first"),
+			testVCRRecorded("/items?a=1&b=2", "x", 200, "second"),
+		}))
+		t.Run("no load error", assertNil(e))
+
+		var serve func(uri, body string) *httptest.ResponseRecorder = func(uri, body string) *httptest.ResponseRecorder {
+			var w *httptest.ResponseRecorder = httptest.NewRecorder()
+			v.ServeHTTP(w, httptest.NewRequest("POST", uri, strings.NewReader(body)))
+			return w
+		}
+
+		var w *httptest.ResponseRecorder = serve("/items?b=2&a=1", "x")
+		t.Run("1st status", assertEq(w.Code, 201))
+		t.Run("1st body", assertEq(w.Body.String(), "first"))
+		t.Run("content type", assertEq(w.Header().Get("Content-Type"), "text/plain"))
+
+		t.Run("2nd", assertEq(serve("/items?b=2&a=1", "x").Body.String(), "second"))
+		t.Run("repeat last", assertEq(serve("/items?b=2&a=1", "x").Body.String(), "second"))
+		t.Run("unmatched body", assertEq(serve("/items?b=2&a=1", "y").Code, 404))
+	})
+
+	t.Run("pass through", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int64
+		var upstream *httptest.Server = httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, q *http.Request) {
+				calls.Add(1)
+				body, _ := io.ReadAll(q.Body)
+				_, _ = w.Write([]byte("live:" + string(body)))
+			},
+		))
+		defer upstream.Close()
+		target, e := url.Parse(upstream.URL)
+		t.Run("no parse error", assertNil(e))
+
+		var v *saver.VCR = saver.VCRNew(saver.VCRKeyDefault, 1024)
+		var saved atomic.Int64
+		v.Fallback = v.RecordingFallbackNew(
+			target,
+			func(_ saver.ExchangeStd) error {
+				saved.Add(1)
+				return nil
+			},
+			1024,
+			saver.RecordIDGenRandom,
+			func(e error) { t.Errorf("unexpected error: %v", e) },
+		)
+		var svr *httptest.Server = httptest.NewServer(v)
+		defer svr.Close()
+
+		var post func() string = func() string {
+			res, e := http.Post(svr.URL+"/new", "text/plain", strings.NewReader("hw"))
+			if nil != e {
+				t.Fatal(e)
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			return string(body)
+		}
+
+		t.Run("1st live", assertEq(post(), "live:hw"))
+		t.Run("2nd recorded", assertEq(post(), "live:hw"))
+		t.Run("upstream once", assertEq(calls.Load(), 1))
+		t.Run("saved once", assertEq(saved.Load(), 1))
+	})
+}
@@ -0,0 +1,194 @@
+// Package webhook provides a BytesSaver which relays serialized requests to an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	saver "github.com/takanoriyanagitani/go-simple-req-saver"
+)
+
+const (
+	// HeaderSignature contains the HMAC-SHA256 of a payload(sample: "sha256=0123...").
+	HeaderSignature = "X-Signature-256"
+
+	// HeaderIdempotencyKey contains a key which is the same for every delivery of a payload.
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// ResponseError is returned when an endpoint responds with an unexpected status.
+type ResponseError struct {
+	Status int
+	Body   string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.Status, e.Body)
+}
+
+func retryable(status int) bool {
+	return http.StatusTooManyRequests == status || http.StatusInternalServerError <= status
+}
+
+// Sign gets the signature header value of a payload.
+func Sign(secret, payload []byte) string {
+	var mac = hmac.New(sha256.New, secret)
+	_, _ = mac.Write(payload) // always nil error
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header value of a payload.
+func Verify(secret, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
+
+// IdempotencyKey gets the idempotency key of a payload(hex encoded SHA-256).
+func IdempotencyKey(payload []byte) string {
+	var digest [sha256.Size]byte = sha256.Sum256(payload)
+	return hex.EncodeToString(digest[:])
+}
+
+// Forwarder posts serialized requests to an endpoint.
+type Forwarder struct {
+	// HTTP sends requests.
+	HTTP *http.Client
+
+	// Retries is the number of retries after a network error, 429 or 5xx.
+	Retries int
+
+	// Backoff is the first wait before a retry; doubled for each retry.
+	Backoff time.Duration
+
+	// Timeout bounds a delivery of AsBytesSaver including retries(0: no timeout).
+	Timeout time.Duration
+
+	// ContentType is the content type of payloads.
+	ContentType string
+
+	endpoint   string
+	secret     []byte
+	deadLetter string
+}
+
+// ForwarderNew creates a forwarder which uses the default http client, retries 3 times and times out after 30 seconds.
+//
+// # Arguments
+//   - endpoint: The url of a collector.
+//   - secret: The HMAC key shared with the collector.
+//   - deadLetter: A directory which keeps payloads which could not be delivered.
+func ForwarderNew(endpoint string, secret []byte, deadLetter string) *Forwarder {
+	return &Forwarder{
+		HTTP:        http.DefaultClient,
+		Retries:     3,
+		Backoff:     100 * time.Millisecond,
+		Timeout:     30 * time.Second,
+		ContentType: "application/x-tar",
+		endpoint:    endpoint,
+		secret:      secret,
+		deadLetter:  deadLetter,
+	}
+}
+
+// context creates a context of a delivery bounded by the Timeout.
+func (f *Forwarder) context() (context.Context, context.CancelFunc) {
+	if f.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), f.Timeout)
+}
+
+func (f *Forwarder) once(ctx context.Context, payload []byte, key string) (status int, body []byte, e error) {
+	q, e := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, bytes.NewReader(payload))
+	if nil != e {
+		return 0, nil, e
+	}
+	q.Header.Set("Content-Type", f.ContentType)
+	q.Header.Set(HeaderSignature, Sign(f.secret, payload))
+	q.Header.Set(HeaderIdempotencyKey, key)
+	res, e := f.HTTP.Do(q)
+	if nil != e {
+		return 0, nil, e
+	}
+	defer res.Body.Close()
+	body, e = io.ReadAll(io.LimitReader(res.Body, 4096))
+	return res.StatusCode, body, e
+}
+
+// Deliver posts a payload and retries on a network error, 429 or 5xx.
+func (f *Forwarder) Deliver(ctx context.Context, payload []byte) error {
+	var key string = IdempotencyKey(payload)
+	var wait time.Duration = f.Backoff
+	for attempt := 0; ; attempt++ {
+		status, body, e := f.once(ctx, payload, key)
+		if nil == e && !retryable(status) {
+			if status < 200 || 300 <= status {
+				return &ResponseError{Status: status, Body: string(body)}
+			}
+			return nil
+		}
+		if nil == e {
+			e = &ResponseError{Status: status, Body: string(body)}
+		}
+		if f.Retries <= attempt {
+			return e
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(e, ctx.Err())
+		case <-time.After(wait):
+			wait *= 2
+		}
+	}
+}
+
+// DeadLetter writes an undelivered payload to the dead-letter directory.
+//
+// The file is named after the idempotency key; it is written to a temporary file and renamed.
+func (f *Forwarder) DeadLetter(payload []byte) (fullpath string, e error) {
+	e = os.MkdirAll(f.deadLetter, 0755)
+	if nil != e {
+		return "", e
+	}
+	fullpath = filepath.Join(f.deadLetter, IdempotencyKey(payload))
+	var tmp string = fullpath + ".tmp"
+	e = os.WriteFile(tmp, payload, 0644)
+	if nil != e {
+		return "", e
+	}
+	return fullpath, os.Rename(tmp, fullpath)
+}
+
+// AsBytesSaver creates a saver which delivers each serialized request.
+//
+// A payload which could not be delivered(within the Timeout) is kept in the dead-letter directory and is not an error.
+//
+// # Arguments
+//   - onDeadLetter: Called when a payload is dead-lettered(nil: ignored).
+func (f *Forwarder) AsBytesSaver(onDeadLetter func(fullpath string, e error)) saver.BytesSaver {
+	return func(serialized []byte) (bytesCount int64, e error) {
+		ctx, cancel := f.context()
+		defer cancel()
+		e = f.Deliver(ctx, serialized)
+		if nil == e {
+			return int64(len(serialized)), nil
+		}
+		fullpath, dlErr := f.DeadLetter(serialized)
+		if nil != dlErr {
+			return 0, errors.Join(e, dlErr)
+		}
+		if nil != onDeadLetter {
+			onDeadLetter(fullpath, e)
+		}
+		return int64(len(serialized)), nil
+	}
+}
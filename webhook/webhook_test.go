@@ -0,0 +1,187 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takanoriyanagitani/go-simple-req-saver/webhook"
+)
+
+func assertEqNew[T any](comp func(a, b T) (same bool)) func(a, b T) func(*testing.T) {
+	return func(a, b T) func(*testing.T) {
+		return func(t *testing.T) {
+			var same bool = comp(a, b)
+			if !same {
+				t.Errorf("Unexpected value got\n")
+				t.Errorf("Expected: %v\n", b)
+				t.Fatalf("Got:      %v\n", a)
+			}
+		}
+	}
+}
+
+func assertEq[T comparable](a, b T) func(*testing.T) {
+	var comp func(a, b T) (same bool) = func(a, b T) (same bool) { return a == b }
+	return assertEqNew(comp)(a, b)
+}
+
+func assertTrue(a bool) func(*testing.T) { return assertEq(a, true) }
+
+func assertNil(e error) func(*testing.T) { return assertEq(nil == e, true) }
+
+var testSecret []byte = []byte("shared")
+
+// testCollector answers with the given statuses(then 204) and records verified payloads.
+type testCollector struct {
+	lock     sync.Mutex
+	statuses []int
+	requests int
+	payloads []string
+	keys     map[string]bool
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, q *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.requests++
+	body, _ := io.ReadAll(q.Body)
+	if !webhook.Verify(testSecret, body, q.Header.Get(webhook.HeaderSignature)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	c.keys[q.Header.Get(webhook.HeaderIdempotencyKey)] = true
+	if 0 < len(c.statuses) {
+		var status int = c.statuses[0]
+		c.statuses = c.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	c.payloads = append(c.payloads, string(body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testForwarderNew(t *testing.T, secret []byte, statuses ...int) (*testCollector, *webhook.Forwarder) {
+	var c *testCollector = &testCollector{statuses: statuses, keys: make(map[string]bool)}
+	var svr *httptest.Server = httptest.NewServer(c)
+	t.Cleanup(svr.Close)
+	var f *webhook.Forwarder = webhook.ForwarderNew(svr.URL, secret, filepath.Join(t.TempDir(), "dead"))
+	f.Backoff = time.Millisecond
+	return c, f
+}
+
+func TestForwarder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Sign", func(t *testing.T) {
+		t.Parallel()
+
+		// RFC 4231 test case 2
+		t.Run("hmac", assertEq(
+			webhook.Sign([]byte("Jefe"), []byte("what do ya want for nothing?")),
+			"sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		))
+		t.Run("verify", assertTrue(!webhook.Verify([]byte("Jefe"), []byte("x"), "sha256=00")))
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		t.Parallel()
+
+		c, f := testForwarderNew(t, testSecret, 503, 429)
+		var dead int = 0
+		cnt, e := f.AsBytesSaver(func(_ string, _ error) { dead++ })([]byte("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("bytes count", assertEq(cnt, 2))
+		t.Run("not dead-lettered", assertEq(dead, 0))
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		t.Run("requests", assertEq(c.requests, 3))
+		t.Run("delivered", assertEq(c.payloads[0], "hw"))
+		t.Run("same key", assertEq(len(c.keys), 1))
+		t.Run("key", assertTrue(c.keys[webhook.IdempotencyKey([]byte("hw"))]))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		t.Parallel()
+
+		c, f := testForwarderNew(t, []byte("wrong"))
+		var deadPath string
+		var deadErr error
+		cnt, e := f.AsBytesSaver(func(fullpath string, e error) {
+			deadPath, deadErr = fullpath, e
+		})([]byte("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("bytes count", assertEq(cnt, 2))
+
+		var re *webhook.ResponseError
+		t.Run("response error", assertTrue(errors.As(deadErr, &re)))
+		t.Run("status", assertEq(re.Status, http.StatusUnauthorized))
+
+		kept, e := os.ReadFile(deadPath)
+		t.Run("dead-lettered", assertNil(e))
+		t.Run("payload", assertEq(string(kept), "hw"))
+		t.Run("named after key", assertEq(filepath.Base(deadPath), webhook.IdempotencyKey([]byte("hw"))))
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		t.Run("not retried", assertEq(c.requests, 1))
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		t.Parallel()
+
+		c, f := testForwarderNew(t, testSecret, 500, 500, 500)
+		f.Retries = 2
+		var dead int = 0
+		_, e := f.AsBytesSaver(func(_ string, _ error) { dead++ })([]byte("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("dead-lettered", assertEq(dead, 1))
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		t.Run("requests", assertEq(c.requests, 3))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		var hang chan struct{} = make(chan struct{})
+		var svr *httptest.Server = httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, q *http.Request) {
+			select {
+			case <-hang:
+			case <-q.Context().Done():
+			}
+		}))
+		t.Cleanup(svr.Close)
+		t.Cleanup(func() { close(hang) })
+		var f *webhook.Forwarder = webhook.ForwarderNew(svr.URL, testSecret, filepath.Join(t.TempDir(), "dead"))
+		f.Backoff = time.Millisecond
+		f.Timeout = 50 * time.Millisecond
+		var deadErr error
+		_, e := f.AsBytesSaver(func(_ string, e error) { deadErr = e })([]byte("hw"))
+		t.Run("no error", assertNil(e))
+		t.Run("dead-lettered", assertTrue(errors.Is(deadErr, context.DeadlineExceeded)))
+	})
+
+	t.Run("dead-letter failure", func(t *testing.T) {
+		t.Parallel()
+
+		var svr *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(400)
+		}))
+		t.Cleanup(svr.Close)
+		var notDir string = filepath.Join(t.TempDir(), "file")
+		_ = os.WriteFile(notDir, nil, 0644)
+		var f *webhook.Forwarder = webhook.ForwarderNew(svr.URL, testSecret, notDir)
+		_, e := f.AsBytesSaver(nil)([]byte("hw"))
+		t.Run("error", assertTrue(nil != e))
+	})
+}